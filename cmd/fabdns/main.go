@@ -42,6 +42,7 @@ import (
 	_ "github.com/coredns/coredns/plugin/template"
 	_ "github.com/coredns/coredns/plugin/tls"
 	_ "github.com/coredns/coredns/plugin/trace"
+	_ "github.com/coredns/coredns/plugin/transfer"
 	_ "github.com/coredns/coredns/plugin/whoami"
 
 	_ "github.com/fabedge/fab-dns/pkg/fabdns"
@@ -72,6 +73,7 @@ var directives = []string{
 	"dnssec",
 	"autopath",
 	"template",
+	"transfer",
 	"hosts",
 	"route53",
 	"k8s_external",
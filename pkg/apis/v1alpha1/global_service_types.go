@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,6 +11,9 @@ type ServiceType string
 const (
 	ClusterIP ServiceType = "ClusterIP"
 	Headless  ServiceType = "Headless"
+	// ExternalName global services are aliases of domain names outside of clusters,
+	// addresses of their endpoints are domain names instead of IPs
+	ExternalName ServiceType = "ExternalName"
 )
 
 // GlobalService is used to represent a service which can be accessed through multi-clusters
@@ -28,13 +32,18 @@ type GlobalService struct {
 // GlobalServiceSpec describes global service and the information necessary to consume it.
 type GlobalServiceSpec struct {
 	// Type represents the type of services which are the backends of a global service
-	// Must be ClusterIP or Headless
-	// +kubebuilder:validation:Enum=ClusterIP;Headless
+	// Must be ClusterIP, Headless or ExternalName
+	// +kubebuilder:validation:Enum=ClusterIP;Headless;ExternalName
 	Type ServiceType `json:"type,omitempty"`
 
 	Ports []ServicePort `json:"ports,omitempty"`
 
 	Endpoints []Endpoint `json:"endpoints,omitempty"`
+
+	// UnavailableClusters are clusters which stop heartbeating, their endpoints are
+	// not used to resolve the global service unless they are specified explicitly.
+	// +optional
+	UnavailableClusters []string `json:"unavailableClusters,omitempty"`
 }
 
 // Endpoint represents a single logical "backend" implementing a service.
@@ -58,14 +67,29 @@ type Endpoint struct {
 	// +optional
 	TargetRef *corev1.ObjectReference `json:"targetRef,omitempty"`
 
+	// conditions contains information about the current status of the endpoint,
+	// it's copied from EndpointSlice of headless services.
+	// +optional
+	Conditions discoveryv1.EndpointConditions `json:"conditions,omitempty"`
+
 	// Cluster indicates the cluster where an endpoint is located
 	Cluster string `json:"cluster,omitempty"`
 	// Zone indicates the zone where the endpoint is located
 	Zone string `json:"zone,omitempty"`
 	// Region indicates the region where the endpoint is located
 	Region string `json:"region,omitempty"`
+	// Weight is the relative weight of the cluster of this endpoint when a global service
+	// is resolved, endpoints without weight use DefaultEndpointWeight.
+	// 0 means the cluster is not resolved unless all clusters have weight 0.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
 }
 
+// DefaultEndpointWeight is the weight of endpoints whose weight is not set
+const DefaultEndpointWeight = 100
+
 // ServicePort represents the port on which the service is exposed
 type ServicePort struct {
 	// The name of this port within the service. This must be a DNS_LABEL.
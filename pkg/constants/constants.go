@@ -18,4 +18,14 @@ const (
 	KeyOriginResourceVersion = "fabedge.io/origin-resource-version"
 	KeyCreatedBy             = "fabedge.io/created-by"
 	AppServiceHub            = "service-hub"
+
+	// KeyGlobalServiceTTL is the annotation of services and global services which
+	// specifies TTL of DNS records of a global service in seconds
+	KeyGlobalServiceTTL = "fabedge.io/global-service-ttl"
+	// KeyGlobalServiceTTLCluster is the annotation of global services which records the
+	// cluster whose service sets the TTL annotation
+	KeyGlobalServiceTTLCluster = "fabedge.io/global-service-ttl-cluster"
+	// KeyGlobalServiceWeight is the annotation of services which specifies the weight of
+	// endpoints of the exporting cluster
+	KeyGlobalServiceWeight = "fabedge.io/global-service-weight"
 )
@@ -0,0 +1,69 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// orders of answer records
+const (
+	// OrderNone keeps records in the order of endpoints in global service
+	OrderNone = "none"
+	// OrderRandom shuffles records for each query
+	OrderRandom = "random"
+	// OrderRoundRobin rotates records by one for each query
+	OrderRoundRobin = "round_robin"
+)
+
+func parseOrder(order string) (string, error) {
+	switch order {
+	case OrderNone, OrderRandom, OrderRoundRobin:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown order '%s'", order)
+	}
+}
+
+// arrangeAnswers orders records by Order and keeps at most MaxAnswers of them,
+// records are ordered before limited so that each record has a chance to be answered.
+func (f FabDNS) arrangeAnswers(records []dns.RR) []dns.RR {
+	if len(records) == 0 {
+		return records
+	}
+
+	switch f.Order {
+	case OrderRandom:
+		rand.Shuffle(len(records), func(i, j int) {
+			records[i], records[j] = records[j], records[i]
+		})
+	case OrderRoundRobin:
+		if f.rotation != nil {
+			offset := int(atomic.AddUint32(f.rotation, 1) % uint32(len(records)))
+			rotated := make([]dns.RR, 0, len(records))
+			records = append(append(rotated, records[offset:]...), records[:offset]...)
+		}
+	}
+
+	if f.MaxAnswers > 0 && len(records) > f.MaxAnswers {
+		records = records[:f.MaxAnswers]
+	}
+
+	return records
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Answers", func() {
+	var (
+		svcAnswers  = "answers-nginx"
+		qname       = fmt.Sprintf("%s.%s.svc.%s", svcAnswers, namespaceDefault, testZone)
+		testService apis.GlobalService
+		fabdns      *FabDNS
+	)
+
+	BeforeEach(func() {
+		var addresses []string
+		for i := 1; i <= 100; i++ {
+			addresses = append(addresses, fmt.Sprintf("192.168.6.%d", i))
+		}
+
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcAnswers,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: addresses,
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+			rotation: new(uint32),
+		}
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	serve := func(edns bool, tcp bool) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		if edns {
+			req.SetEdns0(4096, false)
+		}
+
+		recorder := dnstest.NewRecorder(&test.ResponseWriter{TCP: tcp})
+		_, err := fabdns.ServeDNS(context.TODO(), recorder, req)
+		Expect(err).To(Succeed())
+		return recorder.Msg
+	}
+
+	firstAddress := func(resp *dns.Msg) string {
+		return resp.Answer[0].(*dns.A).A.String()
+	}
+
+	It("should keep records in the order of endpoints by default", func() {
+		resp := serve(true, false)
+		Expect(resp.Answer).To(HaveLen(100))
+		Expect(firstAddress(resp)).To(Equal("192.168.6.1"))
+	})
+
+	It("should rotate records by one for each query with round robin order", func() {
+		fabdns.Order = OrderRoundRobin
+		Expect(firstAddress(serve(true, false))).To(Equal("192.168.6.2"))
+		Expect(firstAddress(serve(true, false))).To(Equal("192.168.6.3"))
+	})
+
+	It("should shuffle records with random order", func() {
+		fabdns.Order = OrderRandom
+		resp := serve(true, false)
+		Expect(resp.Answer).To(HaveLen(100))
+
+		var addresses []string
+		for _, rr := range resp.Answer {
+			addresses = append(addresses, rr.(*dns.A).A.String())
+		}
+		Expect(addresses).To(ContainElements("192.168.6.1", "192.168.6.100"))
+	})
+
+	It("should answer at most max answers records", func() {
+		fabdns.MaxAnswers = 3
+		resp := serve(true, false)
+		Expect(resp.Answer).To(HaveLen(3))
+		Expect(resp.Truncated).To(BeFalse())
+	})
+
+	It("should set TC bit if answers don't fit the UDP buffer size", func() {
+		resp := serve(false, false)
+		Expect(resp.Truncated).To(BeTrue())
+		Expect(resp.Len()).To(BeNumerically("<=", dns.MinMsgSize))
+		Expect(resp.IsEdns0()).To(BeNil())
+	})
+
+	It("should add EDNS0 options before truncating answers", func() {
+		cidr, _ := parseClientLocation([]string{"10.20.0.0/16", "chaoyang", "beijing", "north"})
+		fabdns.ClientLocations = []ClientLocation{cidr}
+
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		req.SetEdns0(dns.MinMsgSize, false)
+		opt := req.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        1,
+			SourceNetmask: 24,
+			Address:       net.ParseIP("10.20.1.0").To4(),
+		})
+
+		recorder := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := fabdns.ServeDNS(context.TODO(), recorder, req)
+		Expect(err).To(Succeed())
+
+		resp := recorder.Msg
+		Expect(resp.Truncated).To(BeTrue())
+		Expect(resp.IsEdns0()).NotTo(BeNil())
+		Expect(resp.IsEdns0().Option).To(HaveLen(1))
+		Expect(resp.Len()).To(BeNumerically("<=", dns.MinMsgSize))
+	})
+
+	It("should not truncate answers over TCP", func() {
+		resp := serve(false, true)
+		Expect(resp.Truncated).To(BeFalse())
+		Expect(resp.Answer).To(HaveLen(100))
+	})
+
+	It("should respond with OPT record of EDNS0 buffer size", func() {
+		resp := serve(true, false)
+		Expect(resp.Truncated).To(BeFalse())
+		Expect(resp.IsEdns0()).NotTo(BeNil())
+		Expect(resp.IsEdns0().UDPSize()).To(Equal(uint16(4096)))
+	})
+})
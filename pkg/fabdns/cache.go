@@ -0,0 +1,157 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// cacheSyncTimeout is how long to wait for the initial list of global services before
+// fabdns starts serving with an unsynced cache, queries are answered with SERVFAIL
+// until the cache is synced
+const cacheSyncTimeout = 5 * time.Second
+
+// newGlobalServiceCache creates an informer-backed cache which holds global services, and
+// namespaces if watchNamespaces is true. Objects in the cache are indexed by namespace/name,
+// so queries are answered from memory.
+// A static RESTMapper is used to avoid discovery requests before the cache is started.
+func newGlobalServiceCache(cfg *rest.Config, watchNamespaces bool) (cache.Cache, error) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(apis.SchemeGroupVersion.WithKind("GlobalService"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	informerCache, err := cache.New(cfg, cache.Options{
+		Scheme: scheme.Scheme,
+		Mapper: mapper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// register GlobalService informer before cache is started, otherwise the informer
+	// would be lazily created by the first query
+	if _, err = informerCache.GetInformer(context.Background(), &apis.GlobalService{}); err != nil {
+		return nil, err
+	}
+
+	// labels of namespaces are needed by namespace selectors
+	if watchNamespaces {
+		if _, err = informerCache.GetInformer(context.Background(), &corev1.Namespace{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = informerCache.IndexField(context.Background(), &apis.GlobalService{}, indexAddress, addressIndexFunc); err != nil {
+		return nil, err
+	}
+
+	return informerCache, nil
+}
+
+// startCache starts the informers of global service cache, or reloading of manifests if
+// global services are read from files, and waits until the cache is synced or
+// cacheSyncTimeout is reached.
+func (f *FabDNS) startCache() error {
+	if f.cache == nil && f.store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.stopCache = cancel
+
+	if f.store != nil {
+		go f.store.run(ctx)
+	}
+
+	if f.cache != nil {
+		go func() {
+			if err := f.cache.Start(ctx); err != nil {
+				log.Errorf("failed to start global service cache: %s", err)
+			}
+		}()
+	}
+
+	if f.monitor != nil {
+		go f.monitor.run(ctx)
+	}
+
+	if f.HealthCheck != nil {
+		go f.HealthCheck.run(ctx, f.Client, f.Cluster.Name)
+	}
+
+	if f.transfer != nil && f.zoneSerial != nil {
+		go f.notifyChanges(ctx)
+	}
+
+	if f.cache == nil {
+		return nil
+	}
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer syncCancel()
+	if f.cache.WaitForCacheSync(syncCtx) {
+		f.markCacheSynced()
+		return nil
+	}
+
+	log.Warningf("starting server with unsynced global service cache")
+	// fabdns is not ready until the cache is synced
+	go func() {
+		if f.cache.WaitForCacheSync(ctx) {
+			log.Infof("global service cache is synced")
+			f.markCacheSynced()
+		}
+	}()
+
+	return nil
+}
+
+// isCacheSynced returns true if the initial sync of global service cache is done, or global
+// services are not read from the cache
+func (f FabDNS) isCacheSynced() bool {
+	if f.cacheSynced == nil {
+		return true
+	}
+
+	select {
+	case <-f.cacheSynced:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *FabDNS) markCacheSynced() {
+	if f.cacheSynced != nil {
+		close(f.cacheSynced)
+	}
+}
+
+// shutdownCache stops the informers of global service cache or reloading of manifests
+func (f *FabDNS) shutdownCache() error {
+	if f.stopCache != nil {
+		f.stopCache()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("GlobalServiceCache", func() {
+	var (
+		fabdns        *FabDNS
+		globalService apis.GlobalService
+		key           client.ObjectKey
+	)
+
+	BeforeEach(func() {
+		informerCache, err := newGlobalServiceCache(testCfg, false)
+		Expect(err).To(Succeed())
+
+		fabdns = &FabDNS{Client: informerCache, cache: informerCache}
+		Expect(fabdns.startCache()).To(Succeed())
+
+		globalService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cached-nginx",
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Addresses: []string{"192.168.1.1"},
+					},
+				},
+			},
+		}
+		key = client.ObjectKey{Namespace: namespaceDefault, Name: globalService.Name}
+		createGlobalService(testK8sClient, &globalService)
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &globalService)
+		Expect(fabdns.shutdownCache()).To(Succeed())
+	})
+
+	It("should read global services from cache after they are created", func() {
+		Eventually(func() error {
+			var gs apis.GlobalService
+			return fabdns.Client.Get(context.TODO(), key, &gs)
+		}).Should(Succeed())
+	})
+})
+
+var _ = Describe("UnsyncedCache", func() {
+	It("should answer SERVFAIL without reading cache until it's synced", func() {
+		fabdns := &FabDNS{
+			Zones:       []string{testZone},
+			TTL:         5,
+			Client:      &fileStore{},
+			cacheSynced: make(chan struct{}),
+		}
+
+		req := new(dns.Msg)
+		req.SetQuestion(fmt.Sprintf("nginx.default.svc.%s", testZone), dns.TypeA)
+		recorder := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := fabdns.ServeDNS(context.TODO(), recorder, req)
+		Expect(err).To(HaveOccurred())
+		Expect(rcode).To(Equal(dns.RcodeServerFailure))
+
+		_, err = fabdns.Transfer(testZone, 0)
+		Expect(err).To(Equal(errCacheNotSynced))
+
+		fabdns.markCacheSynced()
+		rcode, _ = fabdns.ServeDNS(context.TODO(), recorder, req)
+		Expect(rcode).To(Equal(dns.RcodeNameError))
+	})
+})
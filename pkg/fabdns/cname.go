@@ -0,0 +1,79 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// newCNAME returns the CNAME record of query name which aliases to target,
+// false is returned if target is not a valid domain name
+func (f FabDNS) newCNAME(state *request.Request, target string) (*dns.CNAME, bool) {
+	if _, ok := dns.IsDomainName(target); !ok {
+		return nil, false
+	}
+
+	return &dns.CNAME{
+		Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeCNAME, Class: state.QClass(), Ttl: f.TTL},
+		Target: dns.Fqdn(target),
+	}, true
+}
+
+// singleAlias makes sure a name has no more than one CNAME record and no other records
+// with it. Address records win if there are any, otherwise the first CNAME record is kept.
+func singleAlias(records []dns.RR) []dns.RR {
+	var (
+		alias     dns.RR
+		addresses []dns.RR
+	)
+	for _, rr := range records {
+		if rr.Header().Rrtype != dns.TypeCNAME {
+			addresses = append(addresses, rr)
+		} else if alias == nil {
+			alias = rr
+		}
+	}
+
+	if alias == nil || len(addresses) > 0 {
+		return addresses
+	}
+	return []dns.RR{alias}
+}
+
+// chaseCNAME appends records of the target to the answer if the answer is a CNAME record
+// whose target is out of zones of fabdns. Targets in zones of fabdns are not chased, because
+// they may be aliases of each other, clients have to resolve them by themselves.
+func (f FabDNS) chaseCNAME(ctx context.Context, state *request.Request, records []dns.RR) []dns.RR {
+	if f.Upstream == nil || len(records) != 1 {
+		return records
+	}
+
+	cname, ok := records[0].(*dns.CNAME)
+	if !ok || plugin.Zones(f.Zones).Matches(cname.Target) != "" {
+		return records
+	}
+
+	msg, err := f.Upstream.Lookup(ctx, *state, cname.Target, state.QType())
+	if err != nil || msg == nil {
+		log.Debugf("failed to resolve CNAME target %s: %v", cname.Target, err)
+		return records
+	}
+
+	return append(records, msg.Answer...)
+}
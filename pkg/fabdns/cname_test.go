@@ -0,0 +1,143 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("ExternalName", func() {
+	var (
+		fabdns        *FabDNS
+		globalService apis.GlobalService
+		qname         = fmt.Sprintf("database.lab.svc.%s", testZone)
+	)
+
+	expectAnswer := func(qname string, qtype uint16, answer ...dns.RR) {
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname:  qname,
+			Qtype:  qtype,
+			Rcode:  dns.RcodeSuccess,
+			Answer: answer,
+		})
+	}
+
+	BeforeEach(func() {
+		globalService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ExternalName,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"guangzhou.example.com"}, Cluster: "guangzhou", Zone: "guangzhou", Region: "south"},
+					{Addresses: []string{"local.example.com"}, Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion},
+					{Addresses: []string{"shanghai.example.com"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+				},
+			},
+		}
+
+		fabdns = &FabDNS{
+			Zones: []string{testZone},
+			TTL:   5,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		fabdns.Client = &fileStore{
+			globalServices: map[client.ObjectKey]apis.GlobalService{
+				client.ObjectKeyFromObject(&globalService): globalService,
+			},
+		}
+	})
+
+	It("should answer A and AAAA queries with CNAME of the nearest external name", func() {
+		expectAnswer(qname, dns.TypeA, test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    local.example.com.", qname)))
+		expectAnswer(qname, dns.TypeAAAA, test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    local.example.com.", qname)))
+	})
+
+	It("should answer CNAME of the external name of the cluster in ad-hoc query", func() {
+		adHocName := fmt.Sprintf("shanghai.database.lab.svc.%s", testZone)
+		expectAnswer(adHocName, dns.TypeA, test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    shanghai.example.com.", adHocName)))
+	})
+
+	When("endpoints of multiple clusters are selected", func() {
+		BeforeEach(func() {
+			fabdns.Policy = Policy{tierAll}
+		})
+
+		It("should answer only one CNAME record", func() {
+			expectAnswer(qname, dns.TypeA, test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    guangzhou.example.com.", qname)))
+		})
+	})
+
+	When("endpoints have both domain names and IPs", func() {
+		BeforeEach(func() {
+			globalService.Spec.Type = apis.Headless
+			globalService.Spec.Endpoints[1].Addresses = []string{"192.168.12.1"}
+			globalService.Spec.Endpoints = append(globalService.Spec.Endpoints, apis.Endpoint{
+				Addresses: []string{"mysql.example.com"}, Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion,
+			})
+		})
+
+		It("should answer address records instead of CNAME", func() {
+			expectAnswer(qname, dns.TypeA, test.A(fmt.Sprintf("%s    5    IN    A    192.168.12.1", qname)))
+			expectAnswer(qname, dns.TypeAAAA, test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    mysql.example.com.", qname)))
+		})
+	})
+
+	When("the external name is not a valid domain name", func() {
+		BeforeEach(func() {
+			globalService.Spec.Endpoints[1].Addresses = []string{"local..example.com"}
+			fabdns.Policy = Policy{tierCluster}
+		})
+
+		It("should not answer it", func() {
+			executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+				Qname: qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
+			})
+		})
+	})
+
+	It("should not chase CNAME targets in zones of fabdns", func() {
+		fabdns.Upstream = upstream.New()
+
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: req, Zone: testZone}
+
+		records := []dns.RR{test.CNAME(fmt.Sprintf("%s    5    IN    CNAME    mysql.lab.svc.%s", qname, testZone))}
+		Expect(fabdns.chaseCNAME(context.TODO(), &state, records)).To(Equal(records))
+	})
+})
@@ -0,0 +1,100 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// defaultNAT64Prefix is the well-known prefix of RFC 6052
+const defaultNAT64Prefix = "64:ff9b::/96"
+
+// parseDNS64 parses dns64 arguments in the format of [PREFIX], the prefix length must be
+// one of 32, 40, 48, 56, 64 and 96 defined by RFC 6052
+func parseDNS64(args []string) (*net.IPNet, error) {
+	prefix := defaultNAT64Prefix
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	ip, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if isIPv4(ip) {
+		return nil, fmt.Errorf("prefix %s is not an IPv6 prefix", prefix)
+	}
+
+	switch ones, _ := ipNet.Mask.Size(); ones {
+	case 32, 40, 48, 56, 64:
+	case 96:
+		// bits 64 to 71 are reserved
+		if ipNet.IP[8] != 0 {
+			return nil, fmt.Errorf("bits 64 to 71 of prefix %s must be zero", prefix)
+		}
+	default:
+		return nil, fmt.Errorf("invalid length %d of prefix %s", ones, prefix)
+	}
+
+	return ipNet, nil
+}
+
+// canSynthesize returns true if AAAA records of the query can be synthesized from A records
+func (f FabDNS) canSynthesize(state *request.Request) bool {
+	return f.DNS64Prefix != nil && !f.synthesizeAAAA && state.QType() == dns.TypeAAAA
+}
+
+// synthesize embeds the IPv4 address ip into DNS64Prefix by RFC 6052, bits 64 to 71 are skipped
+func (f FabDNS) synthesize(ip net.IP) net.IP {
+	ones, _ := f.DNS64Prefix.Mask.Size()
+	ipv4 := ip.To4()
+
+	synthesized := make(net.IP, net.IPv6len)
+	copy(synthesized, f.DNS64Prefix.IP.To16()[:ones/8])
+	for i, j := ones/8, 0; j < net.IPv4len; i++ {
+		if i == 8 {
+			continue
+		}
+		synthesized[i] = ipv4[j]
+		j++
+	}
+
+	return synthesized
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in ip if ip is synthesized from DNS64Prefix,
+// otherwise nil is returned
+func (f FabDNS) embeddedIPv4(ip net.IP) net.IP {
+	if f.DNS64Prefix == nil || isIPv4(ip) || !f.DNS64Prefix.Contains(ip) {
+		return nil
+	}
+
+	ones, _ := f.DNS64Prefix.Mask.Size()
+	ip = ip.To16()
+
+	ipv4 := make(net.IP, 0, net.IPv4len)
+	for i := ones / 8; len(ipv4) < net.IPv4len; i++ {
+		if i == 8 {
+			continue
+		}
+		ipv4 = append(ipv4, ip[i])
+	}
+
+	return ipv4
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("DNS64", func() {
+	var (
+		fabdns        *FabDNS
+		globalService apis.GlobalService
+		qname         = fmt.Sprintf("nginx.lab.svc.%s", testZone)
+	)
+
+	expectAnswer := func(qname string, qtype uint16, answer ...dns.RR) {
+		testCase := test.Case{
+			Qname:  qname,
+			Qtype:  qtype,
+			Rcode:  dns.RcodeSuccess,
+			Answer: answer,
+		}
+		if len(answer) == 0 {
+			testCase.Ns = []dns.RR{fabdns.soa(testZone)}
+		}
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), testCase)
+	}
+
+	BeforeEach(func() {
+		globalService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion},
+					{Addresses: []string{"fd00::2"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+				},
+			},
+		}
+
+		prefix, err := parseDNS64(nil)
+		Expect(err).To(Succeed())
+
+		fabdns = &FabDNS{
+			Zones:       []string{testZone},
+			TTL:         5,
+			DNS64Prefix: prefix,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		fabdns.Client = &fileStore{
+			globalServices: map[client.ObjectKey]apis.GlobalService{
+				client.ObjectKeyFromObject(&globalService): globalService,
+			},
+		}
+	})
+
+	It("should synthesize AAAA records for the preferred tier which has only IPv4 addresses", func() {
+		expectAnswer(qname, dns.TypeAAAA, test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    64:ff9b::c0a8:c01", qname)))
+	})
+
+	It("should answer IPv6 addresses of the preferred tier without synthesizing", func() {
+		globalService.Spec.Endpoints[0].Addresses = []string{"192.168.12.1", "fd00::1"}
+		expectAnswer(qname, dns.TypeAAAA, test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    fd00::1", qname)))
+	})
+
+	When("no endpoint has IPv6 addresses", func() {
+		BeforeEach(func() {
+			globalService.Spec.Endpoints[1].Addresses = []string{"192.168.12.2"}
+		})
+
+		It("should synthesize AAAA records from IPv4 addresses of the selected tier", func() {
+			expectAnswer(qname, dns.TypeAAAA, test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    64:ff9b::c0a8:c01", qname)))
+		})
+
+		It("should synthesize AAAA records in ad-hoc query", func() {
+			adHocName := fmt.Sprintf("shanghai.%s", qname)
+			expectAnswer(adHocName, dns.TypeAAAA, test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    64:ff9b::c0a8:c02", adHocName)))
+		})
+
+		It("should not change answers of A queries", func() {
+			expectAnswer(qname, dns.TypeA, test.A(fmt.Sprintf("%s    5    IN    A    192.168.12.1", qname)))
+		})
+
+		It("should not synthesize AAAA records if DNS64 is not configured", func() {
+			fabdns.DNS64Prefix = nil
+			expectAnswer(qname, dns.TypeAAAA)
+		})
+	})
+
+	It("should embed IPv4 addresses by prefix length", func() {
+		ip := net.ParseIP("192.0.2.33")
+
+		// examples of RFC 6052
+		for prefix, expected := range map[string]string{
+			"2001:db8::/32":         "2001:db8:c000:221::",
+			"2001:db8:100::/40":     "2001:db8:1c0:2:21::",
+			"2001:db8:122::/48":     "2001:db8:122:c000:2:2100::",
+			"2001:db8:122:300::/56": "2001:db8:122:3c0:0:221::",
+			"2001:db8:122:344::/64": "2001:db8:122:344:c0:2:2100:0",
+			"2001:db8:122:344::/96": "2001:db8:122:344::192.0.2.33",
+			"64:ff9b::/96":          "64:ff9b::192.0.2.33",
+		} {
+			fabdns.DNS64Prefix, _ = parseDNS64([]string{prefix})
+			synthesized := fabdns.synthesize(ip)
+			Expect(synthesized.Equal(net.ParseIP(expected))).To(BeTrue(), prefix)
+			Expect(fabdns.embeddedIPv4(synthesized).Equal(ip)).To(BeTrue(), prefix)
+		}
+
+		Expect(fabdns.embeddedIPv4(net.ParseIP("fd00::1"))).To(BeNil())
+	})
+
+	It("should parse dns64 arguments", func() {
+		prefix, err := parseDNS64(nil)
+		Expect(err).To(Succeed())
+		Expect(prefix.String()).To(Equal(defaultNAT64Prefix))
+
+		prefix, err = parseDNS64([]string{"2001:db8::/32"})
+		Expect(err).To(Succeed())
+		Expect(prefix.String()).To(Equal("2001:db8::/32"))
+
+		for _, arg := range []string{"64:ff9b::", "10.0.0.0/8", "64:ff9b::/80", "2001:db8:0:0:ff00::/96"} {
+			_, err = parseDNS64([]string{arg})
+			Expect(err).To(HaveOccurred(), arg)
+		}
+	})
+})
@@ -19,13 +19,21 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
@@ -36,23 +44,122 @@ const (
 	LabelSVC   = "svc"
 )
 
+// topology tiers of endpoints which are selected to answer a query
+const (
+	tierCluster = "cluster"
+	tierZone    = "zone"
+	tierRegion  = "region"
+	tierAll     = "all"
+)
+
 var (
 	errNoItems        = errors.New("no items found")
 	errInvalidRequest = errors.New("invalid query name")
+	// errCacheNotSynced is returned before the initial sync of global service cache is done,
+	// reading the cache would block until it's synced
+	errCacheNotSynced = errors.New("global service cache is not synced")
 )
 
 // Define log to be a logger with the plugin name in it. This way we can just use log.Info and
 // friends to log.
 var log = clog.NewWithPlugin(PluginName)
 
-// FabDNS implements a plugin supporting multi-cluster FabDNS spec.
+// FabDNS implements a plugin supporting multi-cluster FabDNS spec. Methods of FabDNS have
+// value receivers, so fields changed in a query or a zone transfer, e.g. TTL of a global
+// service and the per-query fields at the end, only take effect in that query or transfer.
 type FabDNS struct {
 	Next    plugin.Handler
 	Zones   []string
 	Fall    fall.F
 	TTL     uint32
-	Client  client.Client
+	Client  client.Reader
 	Cluster ClusterInfo
+
+	// Upstream resolves targets of CNAME records of external names, targets are not
+	// resolved if it's nil
+	Upstream *upstream.Upstream
+
+	// DNS64Prefix is the NAT64 prefix used to synthesize AAAA records from IPv4 addresses
+	// if no IPv6 address can be answered, AAAA records are not synthesized if it's nil
+	DNS64Prefix *net.IPNet
+
+	// MinServiceTTL and MaxServiceTTL bound TTL of global services specified by annotation
+	MinServiceTTL uint32
+	MaxServiceTTL uint32
+
+	// ClientLocations maps clients to cluster locations, endpoints are selected relative to
+	// the location of client instead of Cluster if the client matches any of them
+	ClientLocations []ClientLocation
+
+	// Order is the order of answer records, records are not reordered if it's empty
+	Order string
+	// MaxAnswers is the max number of answer records of A, AAAA and SRV queries, 0 means no limit
+	MaxAnswers int
+
+	// PublishNotReadyAddresses makes fabdns answer queries with endpoints which are not ready
+	PublishNotReadyAddresses bool
+
+	// Naming is the naming scheme of query names, fabedge is used if it's empty
+	Naming string
+	// HostnameCollision enables {hostname}.{service}.{namespace}.svc.{zone} of headless global
+	// services and decides how to answer if hostname exists in multiple clusters, the name is
+	// not supported if it's empty
+	HostnameCollision string
+
+	// Namespaces are namespaces whose global services can be resolved, all namespaces
+	// are allowed if it's empty
+	Namespaces []NamespaceMatcher
+	// ExcludeNamespaces are namespaces whose global services can't be resolved
+	ExcludeNamespaces []NamespaceMatcher
+
+	// Policy is the default topology policy of global services
+	Policy Policy
+	// ServicePolicies are topology policies of specific global services
+	ServicePolicies map[client.ObjectKey]Policy
+
+	// ServeStale is used to answer queries when API server is unreachable, it's nil if
+	// serve_stale is not configured
+	ServeStale *ServeStale
+
+	// HealthCheck excludes unhealthy addresses of remote endpoints from answers, it's nil if
+	// health_check is not configured
+	HealthCheck *HealthCheck
+	// PreferLowLatency makes fabdns answer with clusters of the lowest RTT measured by
+	// health check when endpoints of all clusters are used
+	PreferLowLatency bool
+	// LatencyTolerance is the max RTT difference between preferred clusters and the fastest one
+	LatencyTolerance time.Duration
+
+	// cache is the informer-backed reader of global services, it is also used as
+	// Client when fabdns is created by setup
+	cache cache.Cache
+	// cacheSynced is closed after the initial sync of cache is done
+	cacheSynced chan struct{}
+	// store is the reader of global services in manifests, it is used as Client
+	// when manifests is configured
+	store *fileStore
+	// monitor checks if API server is reachable, it's nil if store is used
+	monitor *apiServerMonitor
+	// zoneSerial is the serial of SOA records which is bumped when global services are changed
+	zoneSerial *zoneSerial
+	// transfer is the transfer plugin in the same server block, it's used to send NOTIFY
+	transfer  *transfer.Transfer
+	stopCache context.CancelFunc
+	// nameserverIPs are addresses of the nameserver in zone transfer
+	nameserverIPs []net.IP
+	// rotation is the counter of round robin order
+	rotation *uint32
+
+	// per-query fields
+	// synthesizeAAAA makes generateRecords synthesize AAAA records from IPv4 addresses
+	synthesizeAAAA bool
+	// ignoreWeights makes endpoints of all clusters in a tier answered regardless of
+	// weights, it's only set in zone transfer
+	ignoreWeights bool
+	// staleTTL caps TTL of records in stale answers when cached global services are stale
+	staleTTL *uint32
+	// ednsOptions are added to the response if the request has EDNS0 enabled
+	ednsOptions []dns.EDNS0
 }
 
 type ClusterInfo struct {
@@ -61,10 +168,30 @@ type ClusterInfo struct {
 	Region string
 }
 
+// queryInfo describes how a query is answered, it's used to report metrics and metadata
+type queryInfo struct {
+	// form is the format of query name, e.g. normal, ad-hoc, headless
+	form string
+	// tier is the topology tier of endpoints which answer the query
+	tier string
+	// namespace and service are the global service which the query refers to
+	namespace string
+	service   string
+	// endpoints are the endpoints whose records answer the query
+	endpoints []apis.Endpoint
+	// addresses are addresses of endpoints in the answer
+	addresses []string
+}
+
 // ServeDNS implements the plugin.Handler interface.
-func (f FabDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+func (f FabDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (rcode int, err error) {
 	state := request.Request{W: w, Req: r}
 
+	info := queryInfoFrom(ctx)
+	defer func() {
+		requestCount.WithLabelValues(metrics.WithServer(ctx), qtypeLabel(state.QType()), dns.RcodeToString[rcode], info.form, info.tier).Inc()
+	}()
+
 	qname := state.QName()
 	log.Debugf("Request query name is %s", qname)
 
@@ -76,26 +203,55 @@ func (f FabDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	zone = qname[len(qname)-len(zone):] // maintain case of original query
 	state.Zone = zone
 
-	if state.QType() != dns.TypeA && state.QType() != dns.TypeAAAA {
-		log.Debugf("query type %d is not implemented", state.QType())
-		return f.nextOrFailure(&state, ctx, w, r, dns.RcodeNotImplemented, fmt.Errorf("query type %d is not implemented", state.QType()))
+	if isZoneName(qname, zone) {
+		records, extras := f.getZoneRecords(&state)
+		return f.writeMsg(&state, records, extras, dns.RcodeSuccess, nil)
 	}
 
-	var (
-		parsedReq recordRequest
-		records   []dns.RR
-		err       error
-	)
+	if !f.isCacheSynced() {
+		log.Debugf("failed to answer query: %s", errCacheNotSynced)
+		return dns.RcodeServerFailure, plugin.Error(f.Name(), errCacheNotSynced)
+	}
 
-	parsedReq, err = parseRequest(qname)
-	if err != nil {
-		return dns.RcodeNameError, err
+	if f.staleTTL, err = f.serveStale(); err != nil {
+		log.Debugf("failed to serve stale answer: %s", err)
+		return dns.RcodeServerFailure, plugin.Error(f.Name(), err)
+	}
+	if f.staleTTL != nil {
+		f.ednsOptions = append(f.ednsOptions, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeStaleAnswer})
 	}
 
-	if parsedReq.isAdHoc {
-		records, err = f.getAdHocRecords(&state, parsedReq)
-	} else {
-		records, err = f.getGlobalRecords(&state, parsedReq)
+	var echo *dns.EDNS0_SUBNET
+	if f.Cluster, echo = f.clientCluster(&state); echo != nil {
+		f.ednsOptions = append(f.ednsOptions, echo)
+	}
+
+	var records, extras []dns.RR
+
+	switch state.QType() {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeSRV:
+		records, extras, err = f.getForwardRecords(&state, info)
+		if err == nil {
+			records = f.chaseCNAME(ctx, &state, records)
+		}
+	case dns.TypePTR:
+		if dnsutil.IsReverse(qname) > 0 {
+			info.form = formReverse
+			records, err = f.getPTRRecords(&state)
+			break
+		}
+		fallthrough
+	default:
+		if dnsutil.IsReverse(qname) > 0 {
+			log.Debugf("query type %d is not implemented", state.QType())
+			return f.nextOrFailure(&state, ctx, w, r, dns.RcodeNotImplemented, fmt.Errorf("query type %d is not implemented", state.QType()))
+		}
+
+		// names of global services only have A, AAAA and SRV records, other query types
+		// are answered with NODATA if the name exists
+		probe := state.NewWithQuestion(qname, dns.TypeA)
+		probe.Zone = zone
+		_, _, err = f.getForwardRecords(&probe, info)
 	}
 
 	if err != nil {
@@ -107,7 +263,7 @@ func (f FabDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		return dns.RcodeServerFailure, plugin.Error(f.Name(), err)
 	}
 
-	return f.writeMsg(&state, records, dns.RcodeSuccess, nil)
+	return f.writeMsg(&state, records, extras, dns.RcodeSuccess, nil)
 }
 
 // Name implements the Handler interface.
@@ -120,7 +276,68 @@ func (f FabDNS) IsNameError(err error) bool {
 	return err == errNoItems || err == errInvalidRequest
 }
 
-func (f FabDNS) getGlobalRecords(state *request.Request, parsedReq recordRequest) ([]dns.RR, error) {
+// getForwardRecords parses query name of state and returns records for it,
+// extras are only returned for SRV queries
+func (f FabDNS) getForwardRecords(state *request.Request, info *queryInfo) (records, extras []dns.RR, err error) {
+	if f.isEmptyNonTerminal(state) {
+		return nil, nil, nil
+	}
+
+	parsedReq, err := f.parseRequest(state.QName(), state.Zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info.form = parsedReq.form()
+	info.namespace, info.service = parsedReq.namespace, parsedReq.service
+
+	// SRV names only have SRV records, other query types are answered with NODATA if they exist
+	if parsedReq.isSRV() && state.QType() != dns.TypeSRV {
+		srvState := state.NewWithQuestion(state.QName(), dns.TypeSRV)
+		srvState.Zone = state.Zone
+		_, err = f.getRecords(&srvState, parsedReq, info)
+		return nil, nil, err
+	}
+
+	records, err = f.getRecords(state, parsedReq, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if state.QType() == dns.TypeSRV {
+		records = dns.Dedup(records, nil)
+	}
+
+	records = f.arrangeAnswers(records)
+
+	if state.QType() == dns.TypeSRV {
+		extras = f.getSRVExtras(state, records)
+	}
+	info.addresses = addressesOf(records, extras)
+
+	return records, extras, nil
+}
+
+func (f FabDNS) getRecords(state *request.Request, parsedReq recordRequest, info *queryInfo) (records []dns.RR, err error) {
+	if parsedReq.isAdHoc {
+		records, err = f.getAdHocRecords(state, parsedReq, info)
+	} else {
+		records, err = f.getGlobalRecords(state, parsedReq, info)
+	}
+
+	// IPv6-only clients reach endpoints which have only IPv4 addresses through NAT64,
+	// tiers of policy are synthesized one by one in getGlobalRecords
+	explicit := parsedReq.isAdHoc || parsedReq.cluster != ""
+	if err == nil && len(records) == 0 && explicit && f.canSynthesize(state) {
+		f.synthesizeAAAA = true
+		return f.getRecords(state, parsedReq, info)
+	}
+
+	// endpoints of external names may be selected together
+	return singleAlias(records), err
+}
+
+func (f FabDNS) getGlobalRecords(state *request.Request, parsedReq recordRequest, info *queryInfo) ([]dns.RR, error) {
 	namespace, serviceName, clusterName, hostname := parsedReq.namespace, parsedReq.service, parsedReq.cluster, parsedReq.hostname
 
 	if len(namespace) == 0 || len(serviceName) == 0 {
@@ -133,89 +350,143 @@ func (f FabDNS) getGlobalRecords(state *request.Request, parsedReq recordRequest
 		return nil, errInvalidRequest
 	}
 
-	var (
-		globalService apis.GlobalService
-		serviceKey    = client.ObjectKey{
-			Namespace: namespace,
-			Name:      serviceName,
-		}
-	)
-
-	err := f.Client.Get(context.TODO(), serviceKey, &globalService)
+	globalService, err := f.getGlobalService(state, parsedReq)
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			log.Debugf("global service %s/%s is not found", namespace, serviceName)
-			return nil, errNoItems
-		}
-		log.Errorf("failed to find GlobalService err: %v, query name is %s", err, state.Name())
 		return nil, err
 	}
 
-	var (
-		headless              = clusterName != ""
-		existHeadlessQName    bool
-		clusterMatchedRecords []dns.RR
-		inZoneRecords         []dns.RR
-		inRegionRecords       []dns.RR
-	)
+	f.TTL = f.ttlOf(globalService)
 
-	if headless {
+	if clusterName != "" {
+		// headless
 		if globalService.Spec.Type != apis.Headless {
 			log.Debugf("the type of GlobalService is %s not match with %s", globalService.Spec.Type, apis.Headless)
 			return nil, errInvalidRequest
 		}
-	} else {
-		// local cluster endpoints preference
-		clusterName = f.Cluster.Name
-	}
 
-	for _, endpoint := range globalService.Spec.Endpoints {
-		switch {
-		case endpoint.Cluster == clusterName:
-			if headless {
-				if endpoint.Hostname != nil && *endpoint.Hostname == hostname {
-					existHeadlessQName = true
-					clusterMatchedRecords = append(clusterMatchedRecords, f.generateRecords(state, endpoint)...)
+		var (
+			existHeadlessQName bool
+			records            []dns.RR
+		)
+		for _, endpoint := range globalService.Spec.Endpoints {
+			if endpoint.Cluster == clusterName && endpoint.Hostname != nil && *endpoint.Hostname == hostname {
+				existHeadlessQName = true
+				endpointRecords := f.generateRecords(state, parsedReq, globalService, endpoint)
+				if len(endpointRecords) > 0 {
+					records = append(records, endpointRecords...)
+					info.endpoints = append(info.endpoints, endpoint)
 				}
-				continue
 			}
-			clusterMatchedRecords = append(clusterMatchedRecords, f.generateRecords(state, endpoint)...)
-
-		case endpoint.Zone == f.Cluster.Zone:
-			// in zone
-			inZoneRecords = append(inZoneRecords, f.generateRecords(state, endpoint)...)
-
-		case endpoint.Region == f.Cluster.Region:
-			// in region
-			inRegionRecords = append(inRegionRecords, f.generateRecords(state, endpoint)...)
 		}
-	}
 
-	if headless {
 		if !existHeadlessQName {
 			log.Debugf("no matched endpoints found")
 			return nil, errNoItems
 		}
-		return clusterMatchedRecords, nil
+		return records, nil
 	}
 
-	switch {
-	case len(clusterMatchedRecords) > 0:
-		return clusterMatchedRecords, nil
-	case len(inZoneRecords) > 0:
-		return inZoneRecords, nil
-	case len(inRegionRecords) > 0:
-		return inRegionRecords, nil
-	default:
-		allRecords := make([]dns.RR, 0)
-		for _, endpoint := range globalService.Spec.Endpoints {
-			allRecords = append(allRecords, f.generateRecords(state, endpoint)...)
+	// endpoints are preferred by the order of tiers in policy, e.g. local cluster endpoints
+	// are preferred than the endpoints in the same zone
+	for _, tier := range f.policyOf(globalService) {
+		records, endpoints := f.getTierRecords(state, parsedReq, globalService, tier)
+
+		// a tier which has only IPv4 addresses is still preferred by IPv6-only clients
+		// through NAT64, AAAA records are synthesized only in this tier
+		if len(records) == 0 && f.canSynthesize(state) {
+			synthesizer := f
+			synthesizer.synthesizeAAAA = true
+			records, endpoints = synthesizer.getTierRecords(state, parsedReq, globalService, tier)
+		}
+
+		if len(records) > 0 {
+			info.tier = tier
+			info.endpoints = endpoints
+			return records, nil
+		}
+	}
+
+	log.Debugf("no endpoints found by policy %v", f.policyOf(globalService))
+	return nil, nil
+}
+
+// getTierRecords returns records of endpoints in the tier and the endpoints which the records
+// are generated from, endpoints of unavailable clusters and drained endpoints are skipped
+func (f FabDNS) getTierRecords(state *request.Request, parsedReq recordRequest, globalService apis.GlobalService, tier string) ([]dns.RR, []apis.Endpoint) {
+	var (
+		records          []dns.RR
+		endpoints        []apis.Endpoint
+		recordsByCluster = make(map[string][]dns.RR)
+	)
+	for _, endpoint := range globalService.Spec.Endpoints {
+		if !f.inTier(endpoint, tier) || isClusterUnavailable(globalService, endpoint.Cluster) || isEndpointDrained(endpoint) {
+			continue
+		}
+
+		endpointRecords := f.generateRecords(state, parsedReq, globalService, endpoint)
+		if len(endpointRecords) == 0 {
+			continue
+		}
+
+		records = append(records, endpointRecords...)
+		endpoints = append(endpoints, endpoint)
+		recordsByCluster[endpoint.Cluster] = append(recordsByCluster[endpoint.Cluster], endpointRecords...)
+	}
+
+	// only endpoints which have records of query type take part in weighted selection
+	if cluster, ok := pickClusterByWeight(endpoints); ok && !f.ignoreWeights {
+		records = recordsByCluster[cluster]
+		endpoints = endpointsOfClusters(endpoints, cluster)
+	} else if clusters, ok := f.clustersByLatency(tier, endpoints); ok {
+		records = nil
+		for _, cluster := range clusters {
+			records = append(records, recordsByCluster[cluster]...)
+		}
+		endpoints = endpointsOfClusters(endpoints, clusters...)
+	}
+
+	return records, endpoints
+}
+
+func (f FabDNS) getAdHocRecords(state *request.Request, parsedReq recordRequest, info *queryInfo) ([]dns.RR, error) {
+	globalService, err := f.getGlobalService(state, parsedReq)
+	if err != nil {
+		return nil, err
+	}
+
+	f.TTL = f.ttlOf(globalService)
+
+	var (
+		existCluster          bool
+		clusterMatchedRecords []dns.RR
+	)
+	for _, endpoint := range globalService.Spec.Endpoints {
+		if endpoint.Cluster == parsedReq.cluster {
+			existCluster = true
+			endpointRecords := f.generateRecords(state, parsedReq, globalService, endpoint)
+			if len(endpointRecords) > 0 {
+				clusterMatchedRecords = append(clusterMatchedRecords, endpointRecords...)
+				info.endpoints = append(info.endpoints, endpoint)
+			}
 		}
-		return allRecords, nil
 	}
+
+	if !existCluster {
+		// the name may be {hostname}.{service}.{namespace}.svc.{zone}
+		if f.isCrossClusterHostname(parsedReq, globalService) {
+			return f.getHostnameRecords(state, parsedReq, globalService, info)
+		}
+
+		log.Debugf("no endpoints of cluster %s found", parsedReq.cluster)
+		return nil, errNoItems
+	}
+
+	return clusterMatchedRecords, nil
 }
 
-func (f *FabDNS) getAdHocRecords(state *request.Request, parsedReq recordRequest) ([]dns.RR, error) {
+// getGlobalService returns the global service which parsedReq refers to, errNoItems is
+// returned if the global service is not found or has no port matching a SRV query
+func (f FabDNS) getGlobalService(state *request.Request, parsedReq recordRequest) (apis.GlobalService, error) {
 	var (
 		globalService apis.GlobalService
 		serviceKey    = client.ObjectKey{
@@ -224,27 +495,47 @@ func (f *FabDNS) getAdHocRecords(state *request.Request, parsedReq recordRequest
 		}
 	)
 
-	err := f.Client.Get(context.TODO(), serviceKey, &globalService)
+	// global services of namespaces which are not allowed are treated as not existing
+	if !f.isNamespaceAllowed(serviceKey.Namespace) {
+		log.Debugf("namespace %s is not allowed", serviceKey.Namespace)
+		return globalService, errNoItems
+	}
+
+	err := f.getObject(serviceKey, &globalService)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			log.Debugf("no global service found by key: %s", serviceKey)
-			return nil, errNoItems
+			return globalService, errNoItems
 		}
 		log.Errorf("failed to find GlobalService err: %v, query name is %s", err, state.Name())
-		return nil, err
+		return globalService, err
 	}
 
-	var clusterMatchedRecords []dns.RR
-	for _, endpoint := range globalService.Spec.Endpoints {
-		if endpoint.Cluster == parsedReq.cluster {
-			clusterMatchedRecords = append(clusterMatchedRecords, f.generateRecords(state, endpoint)...)
-		}
+	if parsedReq.isSRV() && len(matchedPorts(globalService, parsedReq)) == 0 {
+		log.Debugf("no port of global service %s matches %s/%s", serviceKey, parsedReq.port, parsedReq.protocol)
+		return globalService, errNoItems
 	}
 
-	return clusterMatchedRecords, nil
+	return globalService, nil
+}
+
+// getObject gets object by key from Client and reports lookup metrics
+func (f FabDNS) getObject(key client.ObjectKey, obj client.Object) error {
+	start := time.Now()
+	err := f.Client.Get(context.TODO(), key, obj)
+	observeLookup(operationGet, start, err)
+	return err
 }
 
-func (f FabDNS) writeMsg(state *request.Request, records []dns.RR, rcode int, err error) (int, error) {
+// listObjects lists objects from Client and reports lookup metrics
+func (f FabDNS) listObjects(list client.ObjectList, opts ...client.ListOption) error {
+	start := time.Now()
+	err := f.Client.List(context.TODO(), list, opts...)
+	observeLookup(operationList, start, err)
+	return err
+}
+
+func (f FabDNS) writeMsg(state *request.Request, records, extras []dns.RR, rcode int, err error) (int, error) {
 	message := new(dns.Msg)
 	message.Authoritative = true
 
@@ -252,11 +543,32 @@ func (f FabDNS) writeMsg(state *request.Request, records []dns.RR, rcode int, er
 	case dns.RcodeSuccess:
 		message.SetReply(state.Req)
 		message.Answer = append(message.Answer, records...)
+		message.Extra = append(message.Extra, extras...)
+		if len(records) == 0 {
+			// NODATA: the name exists but has no records of the query type
+			message.Ns = []dns.RR{f.soa(state.Zone)}
+		}
 	default:
 		message.SetRcode(state.Req, rcode)
+		if rcode == dns.RcodeNameError {
+			message.Ns = []dns.RR{f.soa(state.Zone)}
+		}
 		err = plugin.Error(f.Name(), err)
 	}
 
+	if f.staleTTL != nil {
+		capTTL(message, *f.staleTTL)
+	}
+
+	// respond with OPT record if the request has EDNS0 enabled, and set TC bit if the
+	// response doesn't fit the buffer size of client, so that client can retry over TCP.
+	// EDNS0 options are added before truncating, so they are counted in the size.
+	state.SizeAndDo(message)
+	for _, option := range f.ednsOptions {
+		appendEDNSOption(state.Req, message, option)
+	}
+	message.Truncate(state.Size())
+
 	state.W.WriteMsg(message)
 	return rcode, err
 }
@@ -266,13 +578,22 @@ func (f FabDNS) nextOrFailure(state *request.Request, ctx context.Context, w dns
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
 
-	return f.writeMsg(state, nil, rcode, err)
+	return f.writeMsg(state, nil, nil, rcode, err)
 }
 
-func (f FabDNS) generateRecords(state *request.Request, endpoint apis.Endpoint) (records []dns.RR) {
+func (f FabDNS) generateRecords(state *request.Request, parsedReq recordRequest, globalService apis.GlobalService, endpoint apis.Endpoint) (records []dns.RR) {
+	if !f.isEndpointReady(endpoint) {
+		return nil
+	}
+
+	addresses := f.healthyAddresses(endpoint)
+	if len(addresses) == 0 {
+		return nil
+	}
+
 	switch state.QType() {
 	case dns.TypeA:
-		for _, addr := range endpoint.Addresses {
+		for _, addr := range addresses {
 			if ip, ok := verifyIP(addr); ok {
 				if isIPv4(ip) {
 					records = append(records, &dns.A{
@@ -280,23 +601,141 @@ func (f FabDNS) generateRecords(state *request.Request, endpoint apis.Endpoint)
 						A:   ip.To4(),
 					})
 				}
+			} else if cname, ok := f.newCNAME(state, addr); ok {
+				records = append(records, cname)
 			}
 		}
 	case dns.TypeAAAA:
-		for _, addr := range endpoint.Addresses {
+		for _, addr := range addresses {
 			if ip, ok := verifyIP(addr); ok {
 				if !isIPv4(ip) {
 					records = append(records, &dns.AAAA{
 						Hdr:  dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeAAAA, Class: state.QClass(), Ttl: f.TTL},
 						AAAA: ip.To16(),
 					})
+				} else if f.synthesizeAAAA {
+					records = append(records, &dns.AAAA{
+						Hdr:  dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeAAAA, Class: state.QClass(), Ttl: f.TTL},
+						AAAA: f.synthesize(ip),
+					})
 				}
+			} else if cname, ok := f.newCNAME(state, addr); ok {
+				records = append(records, cname)
 			}
 		}
+	case dns.TypeSRV:
+		target := f.endpointName(state.Zone, parsedReq, globalService, endpoint)
+		for _, port := range matchedPorts(globalService, parsedReq) {
+			records = append(records, &dns.SRV{
+				Hdr:      dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeSRV, Class: state.QClass(), Ttl: f.TTL},
+				Priority: 0,
+				Weight:   100,
+				Port:     uint16(port.Port),
+				Target:   target,
+			})
+		}
 	}
 	return
 }
 
+// addressesOf returns IPs of address records and targets of CNAME records in sections,
+// they are addresses of endpoints which are answered
+func addressesOf(sections ...[]dns.RR) []string {
+	var addresses []string
+	for _, section := range sections {
+		for _, rr := range section {
+			switch rr := rr.(type) {
+			case *dns.A:
+				addresses = append(addresses, rr.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, rr.AAAA.String())
+			case *dns.CNAME:
+				addresses = append(addresses, strings.TrimSuffix(rr.Target, "."))
+			}
+		}
+	}
+	return addresses
+}
+
+// isEndpointReady returns true if endpoint can be used to answer queries, the endpoint
+// is treated as ready if its ready condition is unknown, which is what Kubernetes does.
+func (f FabDNS) isEndpointReady(endpoint apis.Endpoint) bool {
+	return f.PublishNotReadyAddresses || endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
+
+// isClusterUnavailable returns true if cluster stops heartbeat to service hub, endpoints
+// of unavailable clusters are only used when they are queried explicitly
+func isClusterUnavailable(globalService apis.GlobalService, cluster string) bool {
+	return containsString(globalService.Spec.UnavailableClusters, cluster)
+}
+
+// getSRVExtras resolves the targets of SRV records to A and AAAA records which
+// are used as additional section of SRV response
+func (f FabDNS) getSRVExtras(state *request.Request, records []dns.RR) (extras []dns.RR) {
+	for _, rr := range records {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+
+		parsedReq, err := f.parseRequest(srv.Target, state.Zone)
+		if err != nil {
+			continue
+		}
+
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			req := new(dns.Msg)
+			req.SetQuestion(srv.Target, qtype)
+			targetState := request.Request{W: state.W, Req: req, Zone: state.Zone}
+
+			targetRecords, err := f.getRecords(&targetState, parsedReq, &queryInfo{})
+			if err != nil {
+				log.Debugf("failed to resolve SRV target %s: %s", srv.Target, err)
+				continue
+			}
+			extras = append(extras, targetRecords...)
+		}
+	}
+
+	return dns.Dedup(extras, nil)
+}
+
+// endpointName returns the domain name of endpoint which is used as target of SRV record
+// or PTR record. Headless endpoints with hostname are addressed by their own names, other
+// headless endpoints and ad-hoc queries are addressed by cluster specific names.
+func endpointName(zone string, parsedReq recordRequest, globalService apis.GlobalService, endpoint apis.Endpoint) string {
+	switch {
+	case globalService.Spec.Type == apis.Headless && endpoint.Hostname != nil:
+		return fmt.Sprintf("%s.%s.%s.%s.%s.%s", *endpoint.Hostname, endpoint.Cluster, parsedReq.service, parsedReq.namespace, LabelSVC, zone)
+	case globalService.Spec.Type == apis.Headless || parsedReq.isAdHoc:
+		return fmt.Sprintf("%s.%s.%s.%s.%s", endpoint.Cluster, parsedReq.service, parsedReq.namespace, LabelSVC, zone)
+	default:
+		return fmt.Sprintf("%s.%s.%s.%s", parsedReq.service, parsedReq.namespace, LabelSVC, zone)
+	}
+}
+
+// matchedPorts returns ports of globalService which match the port name and protocol of
+// parsedReq, all ports are returned if parsedReq is not prefixed with _{port}._{protocol}
+func matchedPorts(globalService apis.GlobalService, parsedReq recordRequest) []apis.ServicePort {
+	if !parsedReq.isSRV() {
+		return globalService.Spec.Ports
+	}
+
+	var ports []apis.ServicePort
+	for _, port := range globalService.Spec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		if strings.EqualFold(port.Name, parsedReq.port) && strings.EqualFold(string(protocol), parsedReq.protocol) {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports
+}
+
 func verifyIP(address string) (net.IP, bool) {
 	ip := net.ParseIP(address)
 	return ip, ip != nil
@@ -305,3 +744,25 @@ func verifyIP(address string) (net.IP, bool) {
 func isIPv4(ip net.IP) bool {
 	return ip.To4() != nil
 }
+
+// clustersOf returns clusters of endpoints in the order they appear
+func clustersOf(endpoints []apis.Endpoint) []string {
+	var clusters []string
+	for _, endpoint := range endpoints {
+		if !containsString(clusters, endpoint.Cluster) {
+			clusters = append(clusters, endpoint.Cluster)
+		}
+	}
+	return clusters
+}
+
+// endpointsOfClusters returns endpoints which belong to any of clusters
+func endpointsOfClusters(endpoints []apis.Endpoint, clusters ...string) []apis.Endpoint {
+	var result []apis.Endpoint
+	for _, endpoint := range endpoints {
+		if containsString(clusters, endpoint.Cluster) {
+			result = append(result, endpoint)
+		}
+	}
+	return result
+}
@@ -15,6 +15,7 @@
 package fabdns
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -23,6 +24,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 
@@ -33,6 +35,10 @@ import (
 var testCfg *rest.Config
 var testK8sClient client.Client
 
+// testCache is a started global service cache, it's used by tests which need indexes
+var testCache cache.Cache
+var stopTestCache context.CancelFunc
+
 // envtest provide an api server which has some differences from real environments,
 // read https://book.kubebuilder.io/reference/envtest.html#testing-considerations
 var testEnv *envtest.Environment
@@ -49,6 +55,17 @@ var _ = BeforeSuite(func(done Done) {
 
 	_ = apis.AddToScheme(scheme.Scheme)
 
+	testCache, err = newGlobalServiceCache(testCfg, false)
+	Expect(err).ToNot(HaveOccurred())
+
+	var ctx context.Context
+	ctx, stopTestCache = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(testCache.Start(ctx)).To(Succeed())
+	}()
+	Expect(testCache.WaitForCacheSync(ctx)).To(BeTrue())
+
 	buildConfigFromFlags = func(masterUrl, kubeconfigPath string) (*rest.Config, error) {
 		return testCfg, nil
 	}
@@ -59,6 +76,7 @@ var _ = BeforeSuite(func(done Done) {
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
 	buildConfigFromFlags = clientcmd.BuildConfigFromFlags
+	stopTestCache()
 	err := testEnv.Stop()
 	Expect(err).ShouldNot(HaveOccurred())
 })
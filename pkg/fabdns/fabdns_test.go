@@ -26,6 +26,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -49,6 +50,9 @@ var _ = Describe("Fabdns", func() {
 	Context("Fallthrough configured", testFallthroughConfigured)
 	Context("ClusterIP services", testClusterIPServices)
 	Context("Headless services", testHeadlessServices)
+	Context("Multi-label zones", testMultiLabelZones)
+	Context("Endpoint conditions", testEndpointConditions)
+	Context("Unavailable clusters", testUnavailableClusters)
 })
 
 func testRequestImplements() {
@@ -162,33 +166,126 @@ func testRequestImplements() {
 	})
 
 	When("Query type is SRV", func() {
-		It("should failed", func() {
+		It("should succeed with SRV records of all ports", func() {
 			testCase := test.Case{
 				Qname: qname,
 				Qtype: dns.TypeSRV,
-				Rcode: dns.RcodeNotImplemented,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", qname, qname)),
+				},
+				Extra: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.1")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.2")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.3")),
+					test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    %s", qname, "FF01::3")),
+				},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+
+		It("should succeed with SRV records of the named port", func() {
+			srvName := "_web._tcp." + qname
+			testCase := test.Case{
+				Qname: srvName,
+				Qtype: dns.TypeSRV,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", srvName, qname)),
+				},
+				Extra: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.1")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.2")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.1.3")),
+					test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    %s", qname, "FF01::3")),
+				},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+
+		It("should failed if no port matches", func() {
+			for _, srvName := range []string{"_dns._tcp." + qname, "_web._udp." + qname} {
+				testCase := test.Case{
+					Qname: srvName,
+					Qtype: dns.TypeSRV,
+					Rcode: dns.RcodeNameError,
+				}
+				executeTestCase(fabdns, testRecorder, testCase)
+			}
+		})
+
+		It("should answer NODATA if query type is A", func() {
+			testCase := test.Case{
+				Qname: "_web._tcp." + qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+
+		It("should answer NODATA for the protocol name of named ports", func() {
+			testCase := test.Case{
+				Qname: "_tcp." + qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+
+		It("should answer NXDOMAIN for the protocol name without named ports", func() {
+			testCase := test.Case{
+				Qname: "_udp." + qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeNameError,
 			}
 			executeTestCase(fabdns, testRecorder, testCase)
 		})
 	})
 
 	When("Query type is NS", func() {
-		It("should failed", func() {
+		It("should answer NODATA", func() {
 			testCase := test.Case{
 				Qname: qname,
 				Qtype: dns.TypeNS,
-				Rcode: dns.RcodeNotImplemented,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
 			}
 			executeTestCase(fabdns, testRecorder, testCase)
 		})
 	})
 
 	When("Query type is PTR", func() {
-		It("should failed", func() {
+		It("should answer NODATA", func() {
 			testCase := test.Case{
 				Qname: qname,
 				Qtype: dns.TypePTR,
-				Rcode: dns.RcodeNotImplemented,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+	})
+
+	When("Query type is not supported", func() {
+		It("should answer NODATA for existing names", func() {
+			for _, name := range []string{qname, "_web._tcp." + qname} {
+				testCase := test.Case{
+					Qname: name,
+					Qtype: dns.TypeTXT,
+					Rcode: dns.RcodeSuccess,
+					Ns:    []dns.RR{fabdns.soa(testZone)},
+				}
+				executeTestCase(fabdns, testRecorder, testCase)
+			}
+		})
+
+		It("should answer NXDOMAIN for names which don't exist", func() {
+			testCase := test.Case{
+				Qname: fmt.Sprintf("unknown.%s.svc.%s", namespaceDefault, testZone),
+				Qtype: dns.TypeTXT,
+				Rcode: dns.RcodeNameError,
 			}
 			executeTestCase(fabdns, testRecorder, testCase)
 		})
@@ -720,6 +817,49 @@ func testHeadlessServices() {
 		})
 	})
 
+	When("SRV query for global service type of Headless", func() {
+		It("should succeed with SRV records targeting hostnames of the preferred endpoints", func() {
+			qname := fmt.Sprintf("_web._tcp.%s.%s.svc.%s", svcNginxNorth, namespaceDefault, testZone)
+			target1 := fmt.Sprintf("%s.%s.%s.%s.svc.%s", hostname1, "xicheng", svcNginxNorth, namespaceDefault, testZone)
+			target3 := fmt.Sprintf("%s.%s.%s.%s.svc.%s", hostname3, clusterChaoyang, svcNginxNorth, namespaceDefault, testZone)
+			target4 := fmt.Sprintf("%s.%s.%s.%s.svc.%s", hostname4, clusterChaoyang, svcNginxNorth, namespaceDefault, testZone)
+			testCase := test.Case{
+				Qname: qname,
+				Qtype: dns.TypeSRV,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", qname, target1)),
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", qname, target3)),
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", qname, target4)),
+				},
+				Extra: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", target1, "192.168.1.1")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", target3, "192.168.1.3")),
+					test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    %s", target3, "FF01::3")),
+					test.AAAA(fmt.Sprintf("%s    5    IN    AAAA    %s", target4, "FF01::4")),
+				},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+
+		It("should succeed with SRV records of the specified cluster", func() {
+			qname := fmt.Sprintf("_web._tcp.%s.%s.%s.svc.%s", clusterMinhang, svcNginxNorth, namespaceDefault, testZone)
+			target2 := fmt.Sprintf("%s.%s.%s.%s.svc.%s", hostname2, clusterMinhang, svcNginxNorth, namespaceDefault, testZone)
+			testCase := test.Case{
+				Qname: qname,
+				Qtype: dns.TypeSRV,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", qname, target2)),
+				},
+				Extra: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", target2, "192.168.1.2")),
+				},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		})
+	})
+
 	When("global service type of Headless exists and no A record", func() {
 		It("should succeed with A record response", func() {
 			qname := fmt.Sprintf("%s.%s.%s.%s.svc.%s", hostname4, clusterChaoyang, svcNginxNorth, namespaceDefault, testZone)
@@ -728,6 +868,9 @@ func testHeadlessServices() {
 				Qtype:  dns.TypeA,
 				Rcode:  dns.RcodeSuccess,
 				Answer: []dns.RR{},
+				Ns: []dns.RR{
+					test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone)),
+				},
 			}
 			executeTestCase(fabdns, testRecorder, testCase)
 		})
@@ -741,6 +884,9 @@ func testHeadlessServices() {
 				Qtype:  dns.TypeAAAA,
 				Rcode:  dns.RcodeSuccess,
 				Answer: []dns.RR{},
+				Ns: []dns.RR{
+					test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone)),
+				},
 			}
 			executeTestCase(fabdns, testRecorder, testCase)
 		})
@@ -813,11 +959,299 @@ func testHeadlessServices() {
 
 }
 
+func testMultiLabelZones() {
+	var (
+		zone         = "global.example.com."
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	BeforeEach(func() {
+		fabdns = &FabDNS{
+			Zones:  []string{zone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "multi-label-nginx",
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.2.1"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should parse query names relative to the zone", func() {
+		for _, qname := range []string{
+			"multi-label-nginx.default.svc." + zone,
+			"beijing.multi-label-nginx.default.svc." + zone,
+			"multi-label-nginx.default.beijing." + zone,
+		} {
+			executeTestCase(fabdns, testRecorder, test.Case{
+				Qname: qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.2.1")),
+				},
+			})
+		}
+	})
+
+	It("should return NXDOMAIN if svc label is not next to the zone", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: "multi-label-nginx.default.svc.global.global.example.com.",
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", zone, zone, zone)),
+			},
+		})
+	})
+}
+
+func testEndpointConditions() {
+	var (
+		svcConditions = "conditions-nginx"
+		qname         = fmt.Sprintf("%s.%s.svc.%s", svcConditions, namespaceDefault, testZone)
+		hostname      = "nginx-1"
+		testService   apis.GlobalService
+		testRecorder  *dnstest.Recorder
+		fabdns        *FabDNS
+	)
+
+	BeforeEach(func() {
+		ready, notReady := true, false
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcConditions,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.Headless,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:    testLocalCluster,
+						Region:     testClusterRegion,
+						Zone:       testClusterZone,
+						Addresses:  []string{"192.168.7.1"},
+						Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					},
+					{
+						Hostname:   &hostname,
+						Cluster:    testLocalCluster,
+						Region:     testClusterRegion,
+						Zone:       testClusterZone,
+						Addresses:  []string{"192.168.7.2"},
+						Conditions: discoveryv1.EndpointConditions{Ready: &notReady, Terminating: &ready},
+					},
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.7.3"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should omit endpoints which are not ready", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.7.1")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.7.3")),
+			},
+		})
+	})
+
+	It("should answer NODATA if the endpoint of hostname is not ready", func() {
+		hostQName := fmt.Sprintf("%s.%s.%s", hostname, testLocalCluster, qname)
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: hostQName,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Ns: []dns.RR{
+				test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone)),
+			},
+		})
+	})
+
+	It("should answer all endpoints if not ready addresses are published", func() {
+		fabdns.PublishNotReadyAddresses = true
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.7.1")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.7.2")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.7.3")),
+			},
+		})
+	})
+}
+
+func testUnavailableClusters() {
+	var (
+		svcFailover  = "failover-nginx"
+		qname        = fmt.Sprintf("%s.%s.svc.%s", svcFailover, namespaceDefault, testZone)
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcFailover,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.8.1"},
+					},
+					{
+						Cluster:   "shanghai",
+						Region:    "south",
+						Zone:      "shanghai",
+						Addresses: []string{"192.168.8.2"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should answer endpoints of local cluster if it is available", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.8.1")),
+			},
+		})
+	})
+
+	It("should fail over to other clusters if local cluster is unavailable", func() {
+		testService.Spec.UnavailableClusters = []string{testLocalCluster}
+		updateGlobalService(testK8sClient, &testService)
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.8.2")),
+			},
+		})
+	})
+
+	It("should fail over to other clusters if service of local cluster is not ready", func() {
+		notReady := false
+		testService.Spec.Endpoints[0].Conditions.Ready = &notReady
+		updateGlobalService(testK8sClient, &testService)
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.8.2")),
+			},
+		})
+	})
+
+	It("should answer NODATA if all clusters are unavailable", func() {
+		testService.Spec.UnavailableClusters = []string{testLocalCluster, "shanghai"}
+		updateGlobalService(testK8sClient, &testService)
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Ns: []dns.RR{
+				test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone)),
+			},
+		})
+	})
+}
+
 func createGlobalService(k8sclient client.Client, globalService *apis.GlobalService) {
 	err := k8sclient.Create(context.Background(), globalService, &client.CreateOptions{})
 	Expect(err).Should(BeNil())
 }
 
+func updateGlobalService(k8sclient client.Client, globalService *apis.GlobalService) {
+	err := k8sclient.Update(context.Background(), globalService)
+	Expect(err).Should(BeNil())
+}
+
 func deleteGlobalService(k8sclient client.Client, globalService *apis.GlobalService) {
 	err := k8sclient.Delete(context.Background(), globalService)
 	Expect(err).Should(BeNil())
@@ -0,0 +1,293 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+const defaultManifestsReload = 5 * time.Second
+
+var _ client.Reader = &fileStore{}
+
+// fileStore is a client.Reader which reads global services and namespaces from manifests
+// in a directory, files with extension .yaml, .yml or .json are loaded. The directory is
+// polled every reload interval instead of being watched, because files of mounted ConfigMaps
+// are replaced by symlinks which file watchers may miss, all files are loaded again if any of
+// them is changed.
+type fileStore struct {
+	dir    string
+	reload time.Duration
+	// onChange is called after manifests are reloaded
+	onChange func()
+
+	mu             sync.RWMutex
+	fingerprint    string
+	globalServices map[client.ObjectKey]apis.GlobalService
+	namespaces     map[string]corev1.Namespace
+}
+
+func newFileStore(dir string, reload time.Duration) (*fileStore, error) {
+	store := &fileStore{dir: dir, reload: reload}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// parseManifests parses manifests arguments in the format of DIR [RELOAD]
+func parseManifests(args []string) (string, time.Duration, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", 0, fmt.Errorf("expected DIR [RELOAD]")
+	}
+
+	reload := defaultManifestsReload
+	if len(args) > 1 {
+		var err error
+		reload, err = time.ParseDuration(args[1])
+		if err != nil {
+			return "", 0, err
+		}
+		if reload <= 0 {
+			return "", 0, fmt.Errorf("reload %s must be positive", args[1])
+		}
+	}
+
+	return args[0], reload, nil
+}
+
+// run polls the directory and reloads manifests every reload interval until ctx is done
+func (s *fileStore) run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.load(); err != nil {
+			log.Errorf("failed to reload manifests in %s, keep using old ones: %s", s.dir, err)
+		}
+	}, s.reload)
+}
+
+// load reads all manifests if files in the directory are changed
+func (s *fileStore) load() error {
+	files, fingerprint, err := s.manifestFiles()
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	changed := fingerprint != s.fingerprint
+	s.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	globalServices := make(map[client.ObjectKey]apis.GlobalService)
+	namespaces := make(map[string]corev1.Namespace)
+	for _, file := range files {
+		if err = decodeManifests(file, globalServices, namespaces); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.fingerprint = fingerprint
+	s.globalServices = globalServices
+	s.namespaces = namespaces
+	s.mu.Unlock()
+	log.Infof("loaded %d global services from %s", len(globalServices), s.dir)
+
+	// onChange may read the store, so it's called without lock
+	if s.onChange != nil {
+		s.onChange()
+	}
+
+	return nil
+}
+
+// manifestFiles returns manifest files in the directory and a fingerprint made of
+// names, sizes and modification times of them
+func (s *fileStore) manifestFiles() ([]string, string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		files       []string
+		fingerprint strings.Builder
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !isManifestFile(entry.Name()) {
+			continue
+		}
+
+		// symlinks are followed, so changes of their targets are found
+		info, err := os.Stat(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, "", err
+		}
+
+		files = append(files, filepath.Join(s.dir, entry.Name()))
+		fmt.Fprintf(&fingerprint, "%s/%d/%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	sort.Strings(files)
+
+	return files, fingerprint.String(), nil
+}
+
+func isManifestFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeManifests decodes GlobalService and Namespace objects from a file which may
+// contain multiple YAML documents, objects of other kinds are ignored
+func decodeManifests(file string, globalServices map[client.ObjectKey]apis.GlobalService, namespaces map[string]corev1.Namespace) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw json.RawMessage
+		if err = decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err = json.Unmarshal(raw, &typeMeta); err != nil {
+			return err
+		}
+
+		switch typeMeta.Kind {
+		case "GlobalService":
+			var globalService apis.GlobalService
+			if err = json.Unmarshal(raw, &globalService); err != nil {
+				return err
+			}
+			if globalService.Namespace == "" {
+				globalService.Namespace = corev1.NamespaceDefault
+			}
+			globalServices[client.ObjectKeyFromObject(&globalService)] = globalService
+		case "Namespace":
+			var namespace corev1.Namespace
+			if err = json.Unmarshal(raw, &namespace); err != nil {
+				return err
+			}
+			namespaces[namespace.Name] = namespace
+		default:
+			log.Debugf("ignore %s in %s", typeMeta.Kind, file)
+		}
+	}
+}
+
+func (s *fileStore) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch o := obj.(type) {
+	case *apis.GlobalService:
+		globalService, ok := s.globalServices[key]
+		if !ok {
+			return k8serrors.NewNotFound(apis.SchemeGroupVersion.WithResource("globalservices").GroupResource(), key.String())
+		}
+		globalService.DeepCopyInto(o)
+	case *corev1.Namespace:
+		namespace, ok := s.namespaces[key.Name]
+		if !ok {
+			return k8serrors.NewNotFound(corev1.Resource("namespaces"), key.Name)
+		}
+		namespace.DeepCopyInto(o)
+	default:
+		return fmt.Errorf("unsupported object type %T", obj)
+	}
+
+	return nil
+}
+
+// List lists global services, options of namespace, label selector and
+// address index are supported
+func (s *fileStore) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	globalServiceList, ok := list.(*apis.GlobalServiceList)
+	if !ok {
+		return fmt.Errorf("unsupported list type %T", list)
+	}
+
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	var address string
+	if listOpts.FieldSelector != nil {
+		value, found := listOpts.FieldSelector.RequiresExactMatch(indexAddress)
+		if !found {
+			return fmt.Errorf("unsupported field selector %s", listOpts.FieldSelector)
+		}
+		address = value
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var items []apis.GlobalService
+	for _, globalService := range s.globalServices {
+		if listOpts.Namespace != "" && globalService.Namespace != listOpts.Namespace {
+			continue
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(globalService.Labels)) {
+			continue
+		}
+		if address != "" && !containsString(addressIndexFunc(&globalService), address) {
+			continue
+		}
+
+		items = append(items, *globalService.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return client.ObjectKeyFromObject(&items[i]).String() < client.ObjectKeyFromObject(&items[j]).String()
+	})
+	globalServiceList.Items = items
+
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+const testManifests = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: lab
+  labels:
+    site: lab
+---
+apiVersion: dns.fabedge.io/v1alpha1
+kind: GlobalService
+metadata:
+  name: nginx
+  namespace: lab
+spec:
+  type: ClusterIP
+  ports:
+  - name: web
+    port: 80
+    protocol: TCP
+  endpoints:
+  - addresses:
+    - 192.168.12.1
+    cluster: beijing
+    zone: beijing
+    region: north
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`
+
+const testJSONManifest = `{
+  "apiVersion": "dns.fabedge.io/v1alpha1",
+  "kind": "GlobalService",
+  "metadata": {"name": "mysql", "namespace": "lab"},
+  "spec": {
+    "type": "ClusterIP",
+    "endpoints": [{"addresses": ["192.168.12.2"], "cluster": "shanghai", "zone": "shanghai", "region": "south"}]
+  }
+}`
+
+var _ = Describe("FileStore", func() {
+	var (
+		dir   string
+		store *fileStore
+	)
+
+	writeFile := func(name, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "fabdns-manifests")
+		Expect(err).To(Succeed())
+
+		writeFile("services.yaml", testManifests)
+		writeFile("mysql.json", testJSONManifest)
+		writeFile("README.md", "not a manifest")
+
+		store, err = newFileStore(dir, time.Second)
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should load global services and namespaces from YAML and JSON manifests", func() {
+		var globalService apis.GlobalService
+		Expect(store.Get(context.TODO(), client.ObjectKey{Namespace: "lab", Name: "nginx"}, &globalService)).To(Succeed())
+		Expect(globalService.Spec.Endpoints[0].Addresses).To(Equal([]string{"192.168.12.1"}))
+
+		Expect(store.Get(context.TODO(), client.ObjectKey{Namespace: "lab", Name: "mysql"}, &globalService)).To(Succeed())
+		Expect(globalService.Spec.Endpoints[0].Cluster).To(Equal("shanghai"))
+
+		var namespace corev1.Namespace
+		Expect(store.Get(context.TODO(), client.ObjectKey{Name: "lab"}, &namespace)).To(Succeed())
+		Expect(namespace.Labels).To(Equal(map[string]string{"site": "lab"}))
+	})
+
+	It("should return not found error if object doesn't exist", func() {
+		var globalService apis.GlobalService
+		err := store.Get(context.TODO(), client.ObjectKey{Namespace: "lab", Name: "redis"}, &globalService)
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should list global services by namespace and address", func() {
+		var globalServices apis.GlobalServiceList
+		Expect(store.List(context.TODO(), &globalServices, client.InNamespace("lab"))).To(Succeed())
+		Expect(globalServices.Items).To(HaveLen(2))
+
+		Expect(store.List(context.TODO(), &globalServices, client.MatchingFields{indexAddress: "192.168.12.2"})).To(Succeed())
+		Expect(globalServices.Items).To(HaveLen(1))
+		Expect(globalServices.Items[0].Name).To(Equal("mysql"))
+	})
+
+	It("should reload manifests after files are changed", func() {
+		Expect(os.Remove(filepath.Join(dir, "mysql.json"))).To(Succeed())
+		Expect(store.load()).To(Succeed())
+
+		var globalService apis.GlobalService
+		err := store.Get(context.TODO(), client.ObjectKey{Namespace: "lab", Name: "mysql"}, &globalService)
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should call onChange after reloading, which can read the store", func() {
+		var count int
+		store.onChange = func() {
+			var globalServices apis.GlobalServiceList
+			Expect(store.List(context.TODO(), &globalServices)).To(Succeed())
+			count = len(globalServices.Items)
+		}
+
+		Expect(os.Remove(filepath.Join(dir, "mysql.json"))).To(Succeed())
+		Expect(store.load()).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should keep old global services if manifests are invalid", func() {
+		writeFile("broken.yaml", "kind: GlobalService\nspec: [")
+		Expect(store.load()).NotTo(Succeed())
+
+		var globalService apis.GlobalService
+		Expect(store.Get(context.TODO(), client.ObjectKey{Namespace: "lab", Name: "mysql"}, &globalService)).To(Succeed())
+	})
+
+	It("should answer queries with global services in manifests", func() {
+		fabdns := &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: store,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+
+		qname := fmt.Sprintf("nginx.lab.svc.%s", testZone)
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.12.1")),
+			},
+		})
+	})
+
+	It("should parse manifests arguments", func() {
+		dir, reload, err := parseManifests([]string{"/etc/fabdns", "10s"})
+		Expect(err).To(Succeed())
+		Expect(dir).To(Equal("/etc/fabdns"))
+		Expect(reload).To(Equal(10 * time.Second))
+
+		_, reload, err = parseManifests([]string{"/etc/fabdns"})
+		Expect(err).To(Succeed())
+		Expect(reload).To(Equal(defaultManifestsReload))
+
+		_, _, err = parseManifests(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
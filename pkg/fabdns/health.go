@@ -0,0 +1,364 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckFailures = 3
+	defaultHealthCheckTimeout  = 2 * time.Second
+	defaultHealthCheckWorkers  = 32
+
+	probeResultSuccess = "success"
+	probeResultFailure = "failure"
+)
+
+// HealthCheck probes TCP ports of remote endpoints periodically, addresses which fail
+// Failures consecutive probes are excluded from answers until they pass a probe again.
+type HealthCheck struct {
+	// Interval is the interval between each round of probes
+	Interval time.Duration
+	// Failures is the number of consecutive failed probes before an address is unhealthy
+	Failures int
+	// Timeout is the timeout of each TCP connection
+	Timeout time.Duration
+
+	// workers is the max number of addresses being probed at the same time
+	workers int
+
+	// Hook for unit tests
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mu sync.RWMutex
+	// targets are addresses being probed, keyed by address
+	targets map[string]*probeTarget
+	// localCluster is the cluster whose endpoints are not probed
+	localCluster string
+}
+
+// probeTarget is an entry of health table
+type probeTarget struct {
+	cluster  string
+	ports    []int32
+	failures int
+	// rtt is the smoothed round trip time of successful probes
+	rtt time.Duration
+}
+
+func newHealthCheck() *HealthCheck {
+	return &HealthCheck{
+		Interval: defaultHealthCheckInterval,
+		Failures: defaultHealthCheckFailures,
+		Timeout:  defaultHealthCheckTimeout,
+		workers:  defaultHealthCheckWorkers,
+		dial:     (&net.Dialer{}).DialContext,
+		targets:  make(map[string]*probeTarget),
+	}
+}
+
+// parseHealthCheck parses health_check arguments in the format of [INTERVAL [FAILURES [TIMEOUT]]]
+func parseHealthCheck(args []string) (*HealthCheck, error) {
+	healthCheck := newHealthCheck()
+
+	if len(args) > 0 {
+		interval, err := time.ParseDuration(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval %s must be positive", args[0])
+		}
+		healthCheck.Interval = interval
+	}
+
+	if len(args) > 1 {
+		failures, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if failures <= 0 {
+			return nil, fmt.Errorf("failures %d must be positive", failures)
+		}
+		healthCheck.Failures = failures
+	}
+
+	if len(args) > 2 {
+		timeout, err := time.ParseDuration(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout %s must be positive", args[2])
+		}
+		healthCheck.Timeout = timeout
+	}
+
+	return healthCheck, nil
+}
+
+// run probes endpoints of global services from reader periodically until ctx is done,
+// endpoints of localCluster are not probed because their states are known by kubernetes.
+func (h *HealthCheck) run(ctx context.Context, reader client.Reader, localCluster string) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		h.probeOnce(ctx, reader, localCluster)
+	}, h.Interval)
+}
+
+func (h *HealthCheck) probeOnce(ctx context.Context, reader client.Reader, localCluster string) {
+	var globalServices apis.GlobalServiceList
+	if err := reader.List(ctx, &globalServices); err != nil {
+		log.Errorf("failed to list global services for health check: %s", err)
+		return
+	}
+
+	targets := h.updateTargets(globalServices.Items, localCluster)
+
+	var (
+		wg        sync.WaitGroup
+		addresses = make(chan string)
+	)
+	for i := 0; i < h.workers && i < len(targets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range addresses {
+				target := targets[address]
+				rtt, success := h.probe(ctx, address, target.ports)
+				h.record(address, target.cluster, rtt, success)
+			}
+		}()
+	}
+	for address := range targets {
+		addresses <- address
+	}
+	close(addresses)
+	wg.Wait()
+
+	h.report()
+}
+
+// updateTargets refreshes health table with addresses of global services and returns
+// a snapshot of it, states of addresses which still exist are kept.
+func (h *HealthCheck) updateTargets(globalServices []apis.GlobalService, localCluster string) map[string]probeTarget {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.localCluster = localCluster
+	targets := make(map[string]*probeTarget)
+	for _, globalService := range globalServices {
+		var ports []int32
+		for _, port := range globalService.Spec.Ports {
+			if port.Protocol == corev1.ProtocolTCP || port.Protocol == "" {
+				ports = append(ports, port.Port)
+			}
+		}
+		if len(ports) == 0 {
+			continue
+		}
+
+		for _, endpoint := range globalService.Spec.Endpoints {
+			if endpoint.Cluster == localCluster {
+				continue
+			}
+
+			for _, address := range endpoint.Addresses {
+				// domain names of external names are not probed
+				if _, ok := verifyIP(address); !ok {
+					continue
+				}
+
+				target, ok := targets[address]
+				if !ok {
+					target = &probeTarget{cluster: endpoint.Cluster}
+					if old, exists := h.targets[address]; exists {
+						target.failures, target.rtt = old.failures, old.rtt
+					}
+					targets[address] = target
+				}
+				target.ports = appendPorts(target.ports, ports)
+			}
+		}
+	}
+	h.targets = targets
+
+	snapshot := make(map[string]probeTarget, len(targets))
+	for address, target := range targets {
+		snapshot[address] = *target
+	}
+	return snapshot
+}
+
+// probe returns true and the time of connecting if any port of address accepts TCP connection
+func (h *HealthCheck) probe(ctx context.Context, address string, ports []int32) (time.Duration, bool) {
+	for _, port := range ports {
+		start := time.Now()
+		dialCtx, cancel := context.WithTimeout(ctx, h.Timeout)
+		conn, err := h.dial(dialCtx, "tcp", net.JoinHostPort(address, strconv.Itoa(int(port))))
+		rtt := time.Since(start)
+		cancel()
+
+		if err == nil {
+			_ = conn.Close()
+			return rtt, true
+		}
+	}
+
+	return 0, false
+}
+
+func (h *HealthCheck) record(address, cluster string, rtt time.Duration, success bool) {
+	result := probeResultSuccess
+	if !success {
+		result = probeResultFailure
+	}
+	healthProbeCount.WithLabelValues(cluster, result).Inc()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	target, ok := h.targets[address]
+	if !ok {
+		return
+	}
+
+	if success {
+		if target.failures >= h.Failures {
+			log.Infof("address %s of cluster %s is healthy again", address, cluster)
+		}
+		target.failures = 0
+		// smooth rtt like TCP does to avoid flapping caused by jitter
+		if target.rtt == 0 {
+			target.rtt = rtt
+		} else {
+			target.rtt = (7*target.rtt + 3*rtt) / 10
+		}
+		return
+	}
+
+	target.failures++
+	if target.failures == h.Failures {
+		log.Warningf("address %s of cluster %s is unhealthy, it failed %d probes", address, cluster, target.failures)
+	}
+}
+
+func (h *HealthCheck) report() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	unhealthyByCluster := make(map[string]float64)
+	for _, target := range h.targets {
+		count := unhealthyByCluster[target.cluster]
+		if target.failures >= h.Failures {
+			count++
+		}
+		unhealthyByCluster[target.cluster] = count
+	}
+
+	unhealthyAddresses.Reset()
+	for cluster, count := range unhealthyByCluster {
+		unhealthyAddresses.WithLabelValues(cluster).Set(count)
+	}
+
+	clusterRTT.Reset()
+	for cluster, rtt := range h.clusterRTTsLocked() {
+		clusterRTT.WithLabelValues(cluster).Set(rtt.Seconds())
+	}
+}
+
+// clusterRTTs returns the lowest rtt of healthy addresses of each probed cluster,
+// rtt of local cluster is 0
+func (h *HealthCheck) clusterRTTs() map[string]time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.clusterRTTsLocked()
+}
+
+func (h *HealthCheck) clusterRTTsLocked() map[string]time.Duration {
+	rtts := make(map[string]time.Duration)
+	for _, target := range h.targets {
+		if target.rtt == 0 || target.failures >= h.Failures {
+			continue
+		}
+
+		if rtt, ok := rtts[target.cluster]; !ok || target.rtt < rtt {
+			rtts[target.cluster] = target.rtt
+		}
+	}
+
+	if h.localCluster != "" {
+		rtts[h.localCluster] = 0
+	}
+
+	return rtts
+}
+
+// isHealthy returns false if address failed too many consecutive probes, addresses
+// which are not probed are healthy
+func (h *HealthCheck) isHealthy(address string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	target, ok := h.targets[address]
+	return !ok || target.failures < h.Failures
+}
+
+// healthyAddresses returns addresses of endpoint which are not excluded by health check
+func (f FabDNS) healthyAddresses(endpoint apis.Endpoint) []string {
+	if f.HealthCheck == nil {
+		return endpoint.Addresses
+	}
+
+	var addresses []string
+	for _, address := range endpoint.Addresses {
+		if f.HealthCheck.isHealthy(address) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+func appendPorts(ports []int32, newPorts []int32) []int32 {
+	for _, newPort := range newPorts {
+		found := false
+		for _, port := range ports {
+			if port == newPort {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ports = append(ports, newPort)
+		}
+	}
+
+	return ports
+}
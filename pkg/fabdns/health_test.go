@@ -0,0 +1,223 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("HealthCheck", func() {
+	var (
+		svcHealth    = "health-nginx"
+		qname        = fmt.Sprintf("%s.%s.svc.%s", svcHealth, namespaceDefault, testZone)
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+
+		lock      sync.Mutex
+		dialed    []string
+		unhealthy map[string]bool
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcHealth,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Ports: []apis.ServicePort{
+					{Port: 80, Protocol: corev1.ProtocolTCP},
+					{Port: 53, Protocol: corev1.ProtocolUDP},
+				},
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.9.1"},
+					},
+					{
+						Cluster:   "shanghai",
+						Region:    "south",
+						Zone:      "shanghai",
+						Addresses: []string{"192.168.9.2"},
+					},
+					{
+						Cluster:   "guangzhou",
+						Region:    "south",
+						Zone:      "guangzhou",
+						Addresses: []string{"192.168.9.3"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		dialed, unhealthy = nil, make(map[string]bool)
+		healthCheck := newHealthCheck()
+		healthCheck.Failures = 2
+		healthCheck.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			dialed = append(dialed, address)
+			host, _, _ := net.SplitHostPort(address)
+			if unhealthy[host] {
+				return nil, errors.New("connection refused")
+			}
+
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		}
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+			Policy:      Policy{tierAll},
+			HealthCheck: healthCheck,
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	probe := func() {
+		fabdns.HealthCheck.probeOnce(context.TODO(), testK8sClient, testLocalCluster)
+	}
+
+	It("should only probe TCP ports of endpoints in remote clusters", func() {
+		probe()
+		Expect(dialed).To(ConsistOf("192.168.9.2:80", "192.168.9.3:80"))
+	})
+
+	It("should not probe addresses which are not IPs", func() {
+		testService.Spec.Endpoints[2].Addresses = []string{"192.168.9.3", "guangzhou.example.com"}
+		updateGlobalService(testK8sClient, &testService)
+
+		probe()
+		Expect(dialed).To(ConsistOf("192.168.9.2:80", "192.168.9.3:80"))
+		Expect(fabdns.HealthCheck.targets).NotTo(HaveKey("guangzhou.example.com"))
+	})
+
+	It("should probe addresses by limited workers", func() {
+		var probing, maxProbing int32
+		dial := fabdns.HealthCheck.dial
+		fabdns.HealthCheck.workers = 1
+		fabdns.HealthCheck.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			n := atomic.AddInt32(&probing, 1)
+			defer atomic.AddInt32(&probing, -1)
+
+			lock.Lock()
+			if n > maxProbing {
+				maxProbing = n
+			}
+			lock.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+			return dial(ctx, network, address)
+		}
+
+		probe()
+		Expect(dialed).To(ConsistOf("192.168.9.2:80", "192.168.9.3:80"))
+		Expect(maxProbing).To(BeEquivalentTo(1))
+	})
+
+	It("should exclude addresses which fail consecutive probes", func() {
+		unhealthy["192.168.9.2"] = true
+		probe()
+		Expect(fabdns.HealthCheck.isHealthy("192.168.9.2")).To(BeTrue())
+
+		probe()
+		Expect(fabdns.HealthCheck.isHealthy("192.168.9.2")).To(BeFalse())
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.9.1")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.9.3")),
+			},
+		})
+	})
+
+	It("should include addresses again after they pass a probe", func() {
+		unhealthy["192.168.9.2"] = true
+		probe()
+		probe()
+
+		delete(unhealthy, "192.168.9.2")
+		probe()
+		Expect(fabdns.HealthCheck.isHealthy("192.168.9.2")).To(BeTrue())
+	})
+
+	It("should measure rtt of healthy addresses", func() {
+		probe()
+		Expect(fabdns.HealthCheck.targets["192.168.9.2"].rtt).To(BeNumerically(">", 0))
+
+		rtts := fabdns.HealthCheck.clusterRTTs()
+		Expect(rtts).To(HaveKeyWithValue(testLocalCluster, time.Duration(0)))
+		Expect(rtts).To(HaveKey("shanghai"))
+		Expect(rtts).To(HaveKey("guangzhou"))
+	})
+
+	It("should forget addresses which are removed from global services", func() {
+		probe()
+		Expect(fabdns.HealthCheck.targets).To(HaveKey("192.168.9.3"))
+
+		testService.Spec.Endpoints = testService.Spec.Endpoints[:2]
+		updateGlobalService(testK8sClient, &testService)
+		probe()
+		Expect(fabdns.HealthCheck.targets).NotTo(HaveKey("192.168.9.3"))
+	})
+
+	It("should parse health_check arguments", func() {
+		healthCheck, err := parseHealthCheck([]string{"5s", "4", "1s"})
+		Expect(err).To(Succeed())
+		Expect(healthCheck.Interval).To(Equal(5 * time.Second))
+		Expect(healthCheck.Failures).To(Equal(4))
+		Expect(healthCheck.Timeout).To(Equal(time.Second))
+
+		_, err = parseHealthCheck([]string{"5s", "0"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,133 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// policies of hostnames which exist in multiple clusters
+const (
+	// HostnameCollisionNXDomain answers NXDOMAIN if hostname exists in multiple clusters
+	HostnameCollisionNXDomain = "nxdomain"
+	// HostnameCollisionNearest answers with endpoints of the nearest cluster, local cluster
+	// is preferred, then the same zone and the same region. NXDOMAIN is answered if there
+	// are multiple clusters in the nearest tier.
+	HostnameCollisionNearest = "nearest"
+	// HostnameCollisionAll answers with endpoints of all clusters
+	HostnameCollisionAll = "all"
+)
+
+// parseHostnameCollision parses cross_cluster_hostname arguments in the format of [COLLISION]
+func parseHostnameCollision(args []string) (string, error) {
+	if len(args) == 0 {
+		return HostnameCollisionNXDomain, nil
+	}
+
+	switch args[0] {
+	case HostnameCollisionNXDomain, HostnameCollisionNearest, HostnameCollisionAll:
+		return args[0], nil
+	default:
+		return "", fmt.Errorf("unknown hostname collision policy '%s'", args[0])
+	}
+}
+
+// isCrossClusterHostname returns true if parsedReq, which is parsed as ad-hoc format, could be
+// {hostname}.{service}.{namespace}.svc.{zone} of a headless global service
+func (f FabDNS) isCrossClusterHostname(parsedReq recordRequest, globalService apis.GlobalService) bool {
+	return f.HostnameCollision != "" &&
+		globalService.Spec.Type == apis.Headless &&
+		!parsedReq.isDeprecated &&
+		!parsedReq.isSRV()
+}
+
+// getHostnameRecords returns records of endpoints whose hostname is the first label of query
+// name in all clusters, hostname collisions across clusters are resolved by HostnameCollision.
+// Only endpoints which can be answered are counted in collisions, endpoints of unavailable
+// clusters are only used if no other cluster has the hostname, and endpoints which are not
+// ready or not healthy are only used to answer NODATA if no endpoint can be answered.
+func (f FabDNS) getHostnameRecords(state *request.Request, parsedReq recordRequest, globalService apis.GlobalService, info *queryInfo) ([]dns.RR, error) {
+	// hostname is parsed as cluster in ad-hoc format
+	hostname := parsedReq.cluster
+
+	var endpoints, unavailableEndpoints, unansweredEndpoints []apis.Endpoint
+	for _, endpoint := range globalService.Spec.Endpoints {
+		if endpoint.Hostname == nil || *endpoint.Hostname != hostname {
+			continue
+		}
+
+		switch {
+		case !f.isEndpointReady(endpoint) || len(f.healthyAddresses(endpoint)) == 0:
+			unansweredEndpoints = append(unansweredEndpoints, endpoint)
+		case isClusterUnavailable(globalService, endpoint.Cluster):
+			unavailableEndpoints = append(unavailableEndpoints, endpoint)
+		default:
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		endpoints = unavailableEndpoints
+	}
+	if len(endpoints) == 0 {
+		endpoints = unansweredEndpoints
+	}
+
+	if len(endpoints) == 0 {
+		log.Debugf("no endpoints of hostname %s found", hostname)
+		return nil, errNoItems
+	}
+
+	if f.HostnameCollision == HostnameCollisionNearest {
+		for _, tier := range policyByMode[PolicyPreferLocal] {
+			if tierEndpoints := f.endpointsInTier(endpoints, tier); len(tierEndpoints) > 0 {
+				endpoints = tierEndpoints
+				break
+			}
+		}
+	}
+
+	if clusters := clustersOf(endpoints); len(clusters) > 1 && f.HostnameCollision != HostnameCollisionAll {
+		log.Debugf("hostname %s exists in clusters %v", hostname, clusters)
+		return nil, errNoItems
+	}
+
+	info.form = formHeadless
+	var records []dns.RR
+	for _, endpoint := range endpoints {
+		endpointRecords := f.generateRecords(state, parsedReq, globalService, endpoint)
+		if len(endpointRecords) > 0 {
+			records = append(records, endpointRecords...)
+			info.endpoints = append(info.endpoints, endpoint)
+		}
+	}
+
+	return records, nil
+}
+
+// endpointsInTier returns endpoints which are located in the tier
+func (f FabDNS) endpointsInTier(endpoints []apis.Endpoint, tier string) []apis.Endpoint {
+	var result []apis.Endpoint
+	for _, endpoint := range endpoints {
+		if f.inTier(endpoint, tier) {
+			result = append(result, endpoint)
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("CrossClusterHostname", func() {
+	var (
+		fabdns        *FabDNS
+		globalService apis.GlobalService
+	)
+
+	hostnameOf := func(hostname string) *string {
+		return &hostname
+	}
+
+	queryName := func(hostname string) string {
+		return fmt.Sprintf("%s.mysql.lab.svc.%s", hostname, testZone)
+	}
+
+	expectAnswer := func(qname string, addresses ...string) {
+		var answer []dns.RR
+		for _, address := range addresses {
+			answer = append(answer, test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, address)))
+		}
+
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: answer,
+		})
+	}
+
+	expectNXDomain := func(qname string) {
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+	}
+
+	BeforeEach(func() {
+		globalService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.Headless,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Hostname: hostnameOf("mysql-0"), Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion},
+					{Addresses: []string{"192.168.12.2"}, Hostname: hostnameOf("mysql-0"), Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+					{Addresses: []string{"192.168.12.3"}, Hostname: hostnameOf("mysql-1"), Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+					{Addresses: []string{"192.168.12.4"}, Hostname: hostnameOf("shanghai"), Cluster: "guangzhou", Zone: "guangzhou", Region: "south"},
+				},
+			},
+		}
+
+		fabdns = &FabDNS{
+			Zones:             []string{testZone},
+			TTL:               5,
+			HostnameCollision: HostnameCollisionNXDomain,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		fabdns.Client = &fileStore{
+			globalServices: map[client.ObjectKey]apis.GlobalService{
+				client.ObjectKeyFromObject(&globalService): globalService,
+			},
+		}
+	})
+
+	It("should not resolve hostname without cluster if it's not enabled", func() {
+		fabdns.HostnameCollision = ""
+		expectNXDomain(queryName("mysql-1"))
+	})
+
+	It("should resolve hostname which exists in only one cluster", func() {
+		expectAnswer(queryName("mysql-1"), "192.168.12.3")
+	})
+
+	It("should answer NXDOMAIN if hostname exists in multiple clusters", func() {
+		expectNXDomain(queryName("mysql-0"))
+	})
+
+	It("should answer with endpoints of the nearest cluster if policy is nearest", func() {
+		fabdns.HostnameCollision = HostnameCollisionNearest
+		expectAnswer(queryName("mysql-0"), "192.168.12.1")
+	})
+
+	It("should answer with endpoints of all clusters if policy is all", func() {
+		fabdns.HostnameCollision = HostnameCollisionAll
+		expectAnswer(queryName("mysql-0"), "192.168.12.1", "192.168.12.2")
+	})
+
+	When("a cluster is unavailable", func() {
+		BeforeEach(func() {
+			globalService.Spec.UnavailableClusters = []string{"shanghai"}
+		})
+
+		It("should ignore its endpoints if other clusters have the hostname", func() {
+			expectAnswer(queryName("mysql-0"), "192.168.12.1")
+			expectAnswer(queryName("mysql-1"), "192.168.12.3")
+		})
+	})
+
+	When("endpoints of a cluster are not ready", func() {
+		BeforeEach(func() {
+			ready := false
+			globalService.Spec.Endpoints[1].Conditions.Ready = &ready
+		})
+
+		It("should not count them in hostname collisions", func() {
+			expectAnswer(queryName("mysql-0"), "192.168.12.1")
+		})
+
+		It("should answer NODATA if no endpoint of the hostname is ready", func() {
+			ready := false
+			globalService.Spec.Endpoints[2].Conditions.Ready = &ready
+			executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+				Qname: queryName("mysql-1"),
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{fabdns.soa(testZone)},
+			})
+		})
+	})
+
+	It("should prefer cluster name to hostname", func() {
+		expectAnswer(queryName("shanghai"), "192.168.12.2", "192.168.12.3")
+	})
+
+	It("should parse hostname collision policy", func() {
+		collision, err := parseHostnameCollision(nil)
+		Expect(err).To(Succeed())
+		Expect(collision).To(Equal(HostnameCollisionNXDomain))
+
+		collision, err = parseHostnameCollision([]string{HostnameCollisionNearest})
+		Expect(err).To(Succeed())
+		Expect(collision).To(Equal(HostnameCollisionNearest))
+
+		_, err = parseHostnameCollision([]string{"first"})
+		Expect(err).To(HaveOccurred())
+	})
+})
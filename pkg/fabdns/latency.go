@@ -0,0 +1,87 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+const defaultLatencyTolerance = 10 * time.Millisecond
+
+// parseLatencyTolerance parses prefer_low_latency arguments in the format of [TOLERANCE]
+func parseLatencyTolerance(args []string) (time.Duration, error) {
+	if len(args) == 0 {
+		return defaultLatencyTolerance, nil
+	}
+
+	tolerance, err := time.ParseDuration(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if tolerance < 0 {
+		return 0, fmt.Errorf("tolerance %s must not be negative", args[0])
+	}
+
+	return tolerance, nil
+}
+
+// clustersByLatency returns clusters of endpoints whose RTT measured by health check is
+// within LatencyTolerance of the lowest one, in the order of RTT. It only works for the
+// tier of all endpoints, false is returned if no cluster of endpoints has been measured.
+func (f FabDNS) clustersByLatency(tier string, endpoints []apis.Endpoint) ([]string, bool) {
+	if !f.PreferLowLatency || f.HealthCheck == nil || tier != tierAll {
+		return nil, false
+	}
+
+	rtts := f.HealthCheck.clusterRTTs()
+
+	var clusters []string
+	for _, endpoint := range endpoints {
+		if _, measured := rtts[endpoint.Cluster]; !measured || containsString(clusters, endpoint.Cluster) {
+			continue
+		}
+		clusters = append(clusters, endpoint.Cluster)
+	}
+	if len(clusters) == 0 {
+		return nil, false
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return rtts[clusters[i]] < rtts[clusters[j]]
+	})
+
+	lowest := rtts[clusters[0]]
+	for i, cluster := range clusters {
+		if rtts[cluster]-lowest > f.LatencyTolerance {
+			return clusters[:i], true
+		}
+	}
+
+	return clusters, true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
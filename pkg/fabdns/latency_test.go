@@ -0,0 +1,152 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Latency", func() {
+	var (
+		fabdns    *FabDNS
+		endpoints []apis.Endpoint
+	)
+
+	BeforeEach(func() {
+		healthCheck := newHealthCheck()
+		healthCheck.targets = map[string]*probeTarget{
+			"192.168.10.2": {cluster: "shanghai", rtt: 30 * time.Millisecond},
+			"192.168.10.3": {cluster: "guangzhou", rtt: 35 * time.Millisecond},
+			"192.168.10.4": {cluster: "chengdu", rtt: 80 * time.Millisecond},
+			"192.168.10.5": {cluster: "chengdu", rtt: 5 * time.Millisecond, failures: defaultHealthCheckFailures},
+		}
+
+		fabdns = &FabDNS{
+			HealthCheck:      healthCheck,
+			PreferLowLatency: true,
+			LatencyTolerance: 10 * time.Millisecond,
+		}
+		endpoints = []apis.Endpoint{
+			{Cluster: "chengdu"},
+			{Cluster: "guangzhou"},
+			{Cluster: "shanghai"},
+		}
+	})
+
+	It("should return clusters within tolerance of the lowest rtt in the order of rtt", func() {
+		clusters, ok := fabdns.clustersByLatency(tierAll, endpoints)
+		Expect(ok).To(BeTrue())
+		Expect(clusters).To(Equal([]string{"shanghai", "guangzhou"}))
+	})
+
+	It("should only work for the tier of all endpoints", func() {
+		_, ok := fabdns.clustersByLatency(tierRegion, endpoints)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should prefer local cluster whose rtt is 0", func() {
+		fabdns.HealthCheck.localCluster = "beijing"
+		clusters, ok := fabdns.clustersByLatency(tierAll, append(endpoints, apis.Endpoint{Cluster: "beijing"}))
+		Expect(ok).To(BeTrue())
+		Expect(clusters).To(Equal([]string{"beijing"}))
+	})
+
+	It("should return false if no cluster is measured", func() {
+		_, ok := fabdns.clustersByLatency(tierAll, []apis.Endpoint{{Cluster: "hangzhou"}})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should return false if low latency is not preferred", func() {
+		fabdns.PreferLowLatency = false
+		_, ok := fabdns.clustersByLatency(tierAll, endpoints)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should parse prefer_low_latency arguments", func() {
+		tolerance, err := parseLatencyTolerance(nil)
+		Expect(err).To(Succeed())
+		Expect(tolerance).To(Equal(defaultLatencyTolerance))
+
+		tolerance, err = parseLatencyTolerance([]string{"20ms"})
+		Expect(err).To(Succeed())
+		Expect(tolerance).To(Equal(20 * time.Millisecond))
+
+		_, err = parseLatencyTolerance([]string{"-1ms"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("ServeDNS", func() {
+		var (
+			svcLatency   = "latency-nginx"
+			qname        = fmt.Sprintf("%s.%s.svc.%s", svcLatency, namespaceDefault, testZone)
+			testService  apis.GlobalService
+			testRecorder *dnstest.Recorder
+		)
+
+		BeforeEach(func() {
+			testService = apis.GlobalService{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      svcLatency,
+					Namespace: namespaceDefault,
+				},
+				Spec: apis.GlobalServiceSpec{
+					Type: apis.ClusterIP,
+					Endpoints: []apis.Endpoint{
+						{Cluster: "chengdu", Zone: "chengdu", Region: "west", Addresses: []string{"192.168.10.4"}},
+						{Cluster: "guangzhou", Zone: "guangzhou", Region: "south", Addresses: []string{"192.168.10.3"}},
+						{Cluster: "shanghai", Zone: "shanghai", Region: "east", Addresses: []string{"192.168.10.2"}},
+					},
+				},
+			}
+			createGlobalService(testK8sClient, &testService)
+
+			fabdns.Zones = []string{testZone}
+			fabdns.TTL = 5
+			fabdns.Client = testK8sClient
+			fabdns.Cluster = ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			}
+			testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+		})
+
+		AfterEach(func() {
+			deleteGlobalService(testK8sClient, &testService)
+		})
+
+		It("should answer with endpoints of the lowest latency clusters", func() {
+			executeTestCase(fabdns, testRecorder, test.Case{
+				Qname: qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.10.2")),
+					test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.10.3")),
+				},
+			})
+		})
+	})
+})
@@ -0,0 +1,129 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// ClientLocation maps clients whose addresses are in CIDR to a cluster location
+type ClientLocation struct {
+	CIDR    *net.IPNet
+	Cluster ClusterInfo
+}
+
+// parseClientLocation parses client location from arguments in the format of
+// CIDR CLUSTER ZONE REGION
+func parseClientLocation(args []string) (ClientLocation, error) {
+	if len(args) != 4 {
+		return ClientLocation{}, fmt.Errorf("expected CIDR CLUSTER ZONE REGION, got %v", args)
+	}
+
+	_, cidr, err := net.ParseCIDR(args[0])
+	if err != nil {
+		return ClientLocation{}, err
+	}
+
+	return ClientLocation{
+		CIDR: cidr,
+		Cluster: ClusterInfo{
+			Name:   args[1],
+			Zone:   args[2],
+			Region: args[3],
+		},
+	}, nil
+}
+
+// clientCluster returns the location of the client which sends the query. The address
+// of EDNS0 client subnet option is preferred to the source address of query, the location
+// of the longest matched CIDR is used, Cluster of fabdns is returned if no CIDR matches.
+// If the client subnet is used, the option to echo with scope prefix length is also returned.
+func (f FabDNS) clientCluster(state *request.Request) (ClusterInfo, *dns.EDNS0_SUBNET) {
+	if len(f.ClientLocations) == 0 {
+		return f.Cluster, nil
+	}
+
+	ip := net.ParseIP(state.IP())
+	subnet := clientSubnet(state.Req)
+	if subnet != nil {
+		ip = subnet.Address
+	}
+
+	var matched *ClientLocation
+	for i, location := range f.ClientLocations {
+		if !location.CIDR.Contains(ip) {
+			continue
+		}
+
+		if matched == nil || prefixLength(location.CIDR) > prefixLength(matched.CIDR) {
+			matched = &f.ClientLocations[i]
+		}
+	}
+
+	var echo *dns.EDNS0_SUBNET
+	if subnet != nil {
+		echo = new(dns.EDNS0_SUBNET)
+		*echo = *subnet
+		echo.SourceScope = 0
+		if matched != nil {
+			echo.SourceScope = uint8(prefixLength(matched.CIDR))
+		}
+	}
+
+	if matched == nil {
+		return f.Cluster, echo
+	}
+	return matched.Cluster, echo
+}
+
+// clientSubnet returns the EDNS0 client subnet option of req, options with source prefix
+// length 0 are ignored because clients use them to opt out of client subnet.
+func clientSubnet(req *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := req.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok && subnet.SourceNetmask > 0 && subnet.Address != nil {
+			return subnet
+		}
+	}
+	return nil
+}
+
+func prefixLength(cidr *net.IPNet) int {
+	ones, _ := cidr.Mask.Size()
+	return ones
+}
+
+// appendEDNSOption appends option to OPT record of m if req has EDNS0 enabled
+func appendEDNSOption(req, m *dns.Msg, option dns.EDNS0) {
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil {
+		return
+	}
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		m.SetEdns0(reqOpt.UDPSize(), reqOpt.Do())
+		opt = m.IsEdns0()
+	}
+	opt.Option = append(opt.Option, option)
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("ClientLocation", func() {
+	var (
+		svcLocation = "location-nginx"
+		qname       = fmt.Sprintf("%s.%s.svc.%s", svcLocation, namespaceDefault, testZone)
+		testService apis.GlobalService
+		fabdns      *FabDNS
+	)
+
+	mustParseLocation := func(args ...string) ClientLocation {
+		location, err := parseClientLocation(args)
+		Expect(err).To(Succeed())
+		return location
+	}
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcLocation,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   "chaoyang",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.5.1"},
+					},
+					{
+						Cluster:   "minhang",
+						Region:    "south",
+						Zone:      "shanghai",
+						Addresses: []string{"192.168.5.2"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   "chaoyang",
+				Zone:   "beijing",
+				Region: "north",
+			},
+			ClientLocations: []ClientLocation{
+				mustParseLocation("10.0.0.0/8", "chaoyang", "beijing", "north"),
+				mustParseLocation("10.20.0.0/16", "minhang", "shanghai", "south"),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	serve := func(remoteIP string, subnet *dns.EDNS0_SUBNET) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		if subnet != nil {
+			req.SetEdns0(4096, false)
+			opt := req.IsEdns0()
+			opt.Option = append(opt.Option, subnet)
+		}
+
+		recorder := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: remoteIP})
+		_, err := fabdns.ServeDNS(context.TODO(), recorder, req)
+		Expect(err).To(Succeed())
+		return recorder.Msg
+	}
+
+	expectAnswer := func(resp *dns.Msg, address string) {
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].(*dns.A).A.String()).To(Equal(address))
+	}
+
+	It("should select endpoints relative to the longest matched CIDR of client address", func() {
+		expectAnswer(serve("10.20.1.1", nil), "192.168.5.2")
+		expectAnswer(serve("10.30.1.1", nil), "192.168.5.1")
+	})
+
+	It("should select endpoints relative to Cluster if no CIDR matches", func() {
+		expectAnswer(serve("172.16.1.1", nil), "192.168.5.1")
+	})
+
+	It("should prefer client subnet to client address and echo the option with scope", func() {
+		resp := serve("172.16.1.1", &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        1,
+			SourceNetmask: 24,
+			Address:       net.ParseIP("10.20.1.0").To4(),
+		})
+		expectAnswer(resp, "192.168.5.2")
+
+		Expect(resp.IsEdns0()).NotTo(BeNil())
+		subnet, ok := resp.IsEdns0().Option[0].(*dns.EDNS0_SUBNET)
+		Expect(ok).To(BeTrue())
+		Expect(subnet.SourceNetmask).To(Equal(uint8(24)))
+		Expect(subnet.SourceScope).To(Equal(uint8(16)))
+	})
+
+	It("should ignore client subnet with source prefix length 0", func() {
+		resp := serve("10.20.1.1", &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        1,
+			SourceNetmask: 0,
+			Address:       net.ParseIP("0.0.0.0").To4(),
+		})
+		expectAnswer(resp, "192.168.5.2")
+	})
+
+	It("should fail to parse invalid client locations", func() {
+		for _, args := range [][]string{{"10.0.0.0/8", "chaoyang"}, {"10.0.0.300/8", "chaoyang", "beijing", "north"}} {
+			_, err := parseClientLocation(args)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
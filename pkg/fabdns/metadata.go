@@ -0,0 +1,76 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/request"
+)
+
+var _ metadata.Provider = FabDNS{}
+
+type queryInfoKey struct{}
+
+// Metadata implements metadata.Provider interface. Values are evaluated lazily, so
+// they describe how the query is answered by ServeDNS, e.g. fabdns/cluster are
+// clusters of endpoints in the answer. Values are empty if the query is not answered
+// with global services.
+func (f FabDNS) Metadata(ctx context.Context, state request.Request) context.Context {
+	if plugin.Zones(f.Zones).Matches(state.Name()) == "" {
+		return ctx
+	}
+
+	info := &queryInfo{}
+	ctx = context.WithValue(ctx, queryInfoKey{}, info)
+
+	metadata.SetValueFunc(ctx, PluginName+"/form", func() string {
+		return info.form
+	})
+	metadata.SetValueFunc(ctx, PluginName+"/namespace", func() string {
+		return info.namespace
+	})
+	metadata.SetValueFunc(ctx, PluginName+"/service", func() string {
+		return info.service
+	})
+	metadata.SetValueFunc(ctx, PluginName+"/tier", func() string {
+		return info.tier
+	})
+	metadata.SetValueFunc(ctx, PluginName+"/cluster", func() string {
+		return strings.Join(info.clusters(), ",")
+	})
+	metadata.SetValueFunc(ctx, PluginName+"/endpoints", func() string {
+		return strings.Join(info.addresses, ",")
+	})
+
+	return ctx
+}
+
+// queryInfoFrom returns the queryInfo stored in ctx by Metadata, a new one is returned
+// if metadata plugin is not enabled
+func queryInfoFrom(ctx context.Context) *queryInfo {
+	if info, ok := ctx.Value(queryInfoKey{}).(*queryInfo); ok {
+		return info
+	}
+	return &queryInfo{}
+}
+
+// clusters returns clusters of endpoints which answer the query
+func (info *queryInfo) clusters() []string {
+	return clustersOf(info.endpoints)
+}
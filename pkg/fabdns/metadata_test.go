@@ -0,0 +1,122 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Metadata", func() {
+	var fabdns *FabDNS
+
+	BeforeEach(func() {
+		globalService := apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+					{Addresses: []string{"192.168.12.2"}, Cluster: "guangzhou", Zone: "guangzhou", Region: "south"},
+					{Addresses: []string{"192.168.12.3"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+				},
+			},
+		}
+
+		fabdns = &FabDNS{
+			Zones: []string{testZone},
+			TTL:   5,
+			Client: &fileStore{
+				globalServices: map[client.ObjectKey]apis.GlobalService{
+					client.ObjectKeyFromObject(&globalService): globalService,
+				},
+			},
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+			Policy: Policy{tierAll},
+		}
+	})
+
+	serve := func(qname string, qtype uint16) context.Context {
+		r := new(dns.Msg)
+		r.SetQuestion(qname, qtype)
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		ctx = fabdns.Metadata(ctx, request.Request{W: w, Req: r})
+		_, _ = fabdns.ServeDNS(ctx, w, r)
+
+		return ctx
+	}
+
+	valueOf := func(ctx context.Context, label string) string {
+		f := metadata.ValueFunc(ctx, label)
+		ExpectWithOffset(1, f).NotTo(BeNil())
+		return f()
+	}
+
+	It("should publish global service and endpoints which answer the query", func() {
+		ctx := serve(fmt.Sprintf("nginx.lab.svc.%s", testZone), dns.TypeA)
+
+		Expect(valueOf(ctx, "fabdns/form")).To(Equal(formNormal))
+		Expect(valueOf(ctx, "fabdns/namespace")).To(Equal("lab"))
+		Expect(valueOf(ctx, "fabdns/service")).To(Equal("nginx"))
+		Expect(valueOf(ctx, "fabdns/tier")).To(Equal(tierAll))
+		Expect(valueOf(ctx, "fabdns/cluster")).To(Equal("shanghai,guangzhou"))
+		Expect(strings.Split(valueOf(ctx, "fabdns/endpoints"), ",")).To(ConsistOf("192.168.12.1", "192.168.12.2", "192.168.12.3"))
+	})
+
+	It("should publish cluster of ad-hoc query", func() {
+		ctx := serve(fmt.Sprintf("guangzhou.nginx.lab.svc.%s", testZone), dns.TypeA)
+
+		Expect(valueOf(ctx, "fabdns/form")).To(Equal(formAdHoc))
+		Expect(valueOf(ctx, "fabdns/cluster")).To(Equal("guangzhou"))
+		Expect(valueOf(ctx, "fabdns/endpoints")).To(Equal("192.168.12.2"))
+	})
+
+	It("should publish empty values if no endpoint answers the query", func() {
+		ctx := serve(fmt.Sprintf("redis.lab.svc.%s", testZone), dns.TypeA)
+
+		Expect(valueOf(ctx, "fabdns/namespace")).To(Equal("lab"))
+		Expect(valueOf(ctx, "fabdns/tier")).To(BeEmpty())
+		Expect(valueOf(ctx, "fabdns/cluster")).To(BeEmpty())
+		Expect(valueOf(ctx, "fabdns/endpoints")).To(BeEmpty())
+	})
+
+	It("should not publish values for names out of zones", func() {
+		r := new(dns.Msg)
+		r.SetQuestion("example.org.", dns.TypeA)
+
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		ctx = fabdns.Metadata(ctx, request.Request{W: &test.ResponseWriter{}, Req: r})
+		Expect(metadata.ValueFunc(ctx, "fabdns/cluster")).To(BeNil())
+	})
+})
@@ -0,0 +1,110 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Metrics are registered to the default registry, which is exported by prometheus plugin.
+var (
+	// requestCount is the counter of requests handled by fabdns, form is the format of
+	// query name and tier is the topology tier of endpoints selected to answer the request.
+	requestCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "requests_total",
+		Help:      "Counter of requests handled by fabdns.",
+	}, []string{"server", "type", "rcode", "form", "tier"})
+
+	// lookupDuration is the histogram of time spent on looking up global services.
+	lookupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "backend_lookup_duration_seconds",
+		Buckets:   plugin.TimeBuckets,
+		Help:      "Histogram of the time each global service lookup took.",
+	}, []string{"operation"})
+
+	// lookupErrors is the counter of failed global service lookups, not found is not an error.
+	lookupErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "backend_lookup_errors_total",
+		Help:      "Counter of global service lookup errors.",
+	}, []string{"operation"})
+
+	// healthProbeCount is the counter of TCP probes to endpoint addresses of remote clusters.
+	healthProbeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "health_probes_total",
+		Help:      "Counter of health probes to endpoint addresses.",
+	}, []string{"cluster", "result"})
+
+	// unhealthyAddresses is the number of endpoint addresses excluded by health check.
+	unhealthyAddresses = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "unhealthy_addresses",
+		Help:      "Number of endpoint addresses which are excluded from answers by health check.",
+	}, []string{"cluster"})
+
+	// apiServerReachable reports if API server is reachable by the last probe.
+	apiServerReachable = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "api_server_reachable",
+		Help:      "Whether API server is reachable by the last probe, 1 means reachable.",
+	})
+
+	// clusterRTT is the lowest smoothed RTT of healthy endpoint addresses of each cluster.
+	clusterRTT = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "cluster_rtt_seconds",
+		Help:      "Lowest round trip time of health probes to endpoint addresses of each cluster.",
+	}, []string{"cluster"})
+)
+
+const (
+	operationGet  = "get"
+	operationList = "list"
+)
+
+// observeLookup reports duration and error of a global service lookup
+func observeLookup(operation string, start time.Time, err error) {
+	lookupDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil && !k8serrors.IsNotFound(err) {
+		lookupErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// qtypeLabel returns name of qtype if fabdns supports it, otherwise "other" is returned
+// to avoid unbounded label values
+func qtypeLabel(qtype uint16) string {
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeSRV, dns.TypePTR, dns.TypeSOA, dns.TypeNS:
+		return dns.TypeToString[qtype]
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		svcMetricsNginx = "metrics-nginx"
+		testService     apis.GlobalService
+		testRecorder    *dnstest.Recorder
+		fabdns          *FabDNS
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcMetricsNginx,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   "xicheng",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.1.1"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   "haidian",
+				Zone:   "beijing",
+				Region: "north",
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should count requests by query form and topology tier", func() {
+		counter := requestCount.WithLabelValues("", "A", "NOERROR", formNormal, tierZone)
+		before := testutil.ToFloat64(counter)
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("%s.%s.svc.%s", svcMetricsNginx, namespaceDefault, testZone),
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s.%s.svc.%s    5    IN    A    192.168.1.1", svcMetricsNginx, namespaceDefault, testZone)),
+			},
+		})
+
+		Expect(testutil.ToFloat64(counter)).To(Equal(before + 1))
+	})
+
+	It("should count failed requests by query form", func() {
+		counter := requestCount.WithLabelValues("", "AAAA", "NXDOMAIN", formAdHoc, "")
+		before := testutil.ToFloat64(counter)
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("unknown.%s.%s.svc.%s", svcMetricsNginx, namespaceDefault, testZone),
+			Qtype: dns.TypeAAAA,
+			Rcode: dns.RcodeNameError,
+		})
+
+		Expect(testutil.ToFloat64(counter)).To(Equal(before + 1))
+	})
+
+	It("should observe backend lookups", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("unknown.%s.svc.%s", namespaceDefault, testZone),
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+
+		Expect(testutil.CollectAndCount(lookupDuration, "coredns_fabdns_backend_lookup_duration_seconds")).To(BeNumerically(">", 0))
+		Expect(testutil.ToFloat64(lookupErrors.WithLabelValues(operationGet))).To(BeZero())
+	})
+})
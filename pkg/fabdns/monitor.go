@@ -0,0 +1,110 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	apiServerProbeInterval = 5 * time.Second
+	apiServerProbeTimeout  = 3 * time.Second
+)
+
+// apiServerMonitor probes API server periodically and records since when it is unreachable,
+// it's used by serve_stale and readiness.
+type apiServerMonitor struct {
+	probe func(ctx context.Context) error
+
+	mu sync.RWMutex
+	// lastContact is the last time API server is reachable
+	lastContact time.Time
+	// unreachableSince is zero if API server is reachable, otherwise it's the last contact time
+	unreachableSince time.Time
+}
+
+func newAPIServerMonitor(cfg *rest.Config) (*apiServerMonitor, error) {
+	probe, err := newAPIServerProbe(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiServerMonitor{probe: probe}, nil
+}
+
+// newAPIServerProbe returns a probe which checks if API server is reachable by /healthz
+func newAPIServerProbe(cfg *rest.Config) (func(ctx context.Context) error, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		return discoveryClient.RESTClient().Get().AbsPath("/healthz").Do(ctx).Error()
+	}, nil
+}
+
+// run probes API server periodically until ctx is done
+func (m *apiServerMonitor) run(ctx context.Context) {
+	m.mu.Lock()
+	m.lastContact = time.Now()
+	m.mu.Unlock()
+
+	wait.UntilWithContext(ctx, m.probeOnce, apiServerProbeInterval)
+}
+
+func (m *apiServerMonitor) probeOnce(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, apiServerProbeTimeout)
+	defer cancel()
+
+	err := m.probe(probeCtx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		if !m.unreachableSince.IsZero() {
+			log.Infof("API server is reachable again")
+		}
+		m.lastContact = time.Now()
+		m.unreachableSince = time.Time{}
+		apiServerReachable.Set(1)
+		return
+	}
+
+	if m.unreachableSince.IsZero() {
+		log.Warningf("API server is unreachable: %s", err)
+		m.unreachableSince = m.lastContact
+	}
+	apiServerReachable.Set(0)
+}
+
+// unreachableFor returns how long API server has been unreachable since the last contact,
+// false is returned if API server is reachable
+func (m *apiServerMonitor) unreachableFor() (time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.unreachableSince.IsZero() {
+		return 0, false
+	}
+	return time.Since(m.unreachableSince), true
+}
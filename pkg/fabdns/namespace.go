@@ -0,0 +1,118 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespaceMatcher matches namespaces by name pattern or by labels of Namespace objects,
+// only one of Pattern and Selector is set.
+type NamespaceMatcher struct {
+	// Pattern is a glob pattern of namespace names, e.g. tenant-*
+	Pattern string
+	// Selector is a label selector of namespaces, e.g. tenant=a
+	Selector labels.Selector
+}
+
+// parseNamespaceMatchers parses arguments of namespaces and exclude_namespaces, an argument
+// which contains operators of label selector is parsed as a selector, otherwise a glob pattern.
+func parseNamespaceMatchers(args []string) ([]NamespaceMatcher, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("at least one namespace pattern or selector is required")
+	}
+
+	var matchers []NamespaceMatcher
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "=!(), ") {
+			selector, err := labels.Parse(arg)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, NamespaceMatcher{Selector: selector})
+			continue
+		}
+
+		if _, err := path.Match(arg, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern '%s': %s", arg, err)
+		}
+		matchers = append(matchers, NamespaceMatcher{Pattern: arg})
+	}
+
+	return matchers, nil
+}
+
+// hasNamespaceSelector returns true if any matcher needs labels of namespaces
+func hasNamespaceSelector(matcherLists ...[]NamespaceMatcher) bool {
+	for _, matchers := range matcherLists {
+		for _, matcher := range matchers {
+			if matcher.Selector != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isNamespaceAllowed returns true if global services of namespace can be resolved, namespace
+// is allowed if it matches Namespaces (or Namespaces is empty) and doesn't match ExcludeNamespaces.
+func (f FabDNS) isNamespaceAllowed(namespace string) bool {
+	if len(f.Namespaces) == 0 && len(f.ExcludeNamespaces) == 0 {
+		return true
+	}
+
+	var namespaceLabels labels.Set
+	if hasNamespaceSelector(f.Namespaces, f.ExcludeNamespaces) {
+		var ns corev1.Namespace
+		err := f.getObject(client.ObjectKey{Name: namespace}, &ns)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			// deny queries if namespace can't be checked, tenants may be exposed otherwise
+			log.Errorf("failed to get namespace %s: %s", namespace, err)
+			return false
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	if len(f.Namespaces) > 0 && !matchNamespace(f.Namespaces, namespace, namespaceLabels) {
+		return false
+	}
+
+	return !matchNamespace(f.ExcludeNamespaces, namespace, namespaceLabels)
+}
+
+func matchNamespace(matchers []NamespaceMatcher, namespace string, namespaceLabels labels.Set) bool {
+	for _, matcher := range matchers {
+		if matcher.Selector != nil {
+			if matcher.Selector.Matches(namespaceLabels) {
+				return true
+			}
+			continue
+		}
+
+		if matched, _ := path.Match(matcher.Pattern, namespace); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Namespaces", func() {
+	const namespaceTenant = "tenant-a"
+
+	var (
+		svcTenant    = "tenant-nginx"
+		qname        = fmt.Sprintf("%s.%s.svc.%s", svcTenant, namespaceTenant, testZone)
+		adHocQName   = fmt.Sprintf("%s.%s.%s.svc.%s", testLocalCluster, svcTenant, namespaceTenant, testZone)
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	mustParse := func(args ...string) []NamespaceMatcher {
+		matchers, err := parseNamespaceMatchers(args)
+		Expect(err).To(Succeed())
+		return matchers
+	}
+
+	BeforeEach(func() {
+		ns := corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namespaceTenant,
+				Labels: map[string]string{"tenant": "a"},
+			},
+		}
+		if err := testK8sClient.Create(context.Background(), &ns); err != nil {
+			Expect(k8serrors.IsAlreadyExists(err)).To(BeTrue())
+		}
+
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcTenant,
+				Namespace: namespaceTenant,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.11.1"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should allow all namespaces if no namespaces are configured", func() {
+		Expect(fabdns.isNamespaceAllowed(namespaceTenant)).To(BeTrue())
+	})
+
+	It("should only allow namespaces matching namespaces", func() {
+		fabdns.Namespaces = mustParse("tenant-*")
+		Expect(fabdns.isNamespaceAllowed(namespaceTenant)).To(BeTrue())
+		Expect(fabdns.isNamespaceAllowed(namespaceDefault)).To(BeFalse())
+
+		fabdns.Namespaces = mustParse("tenant=a")
+		Expect(fabdns.isNamespaceAllowed(namespaceTenant)).To(BeTrue())
+		Expect(fabdns.isNamespaceAllowed(namespaceDefault)).To(BeFalse())
+	})
+
+	It("should not allow namespaces matching exclude_namespaces", func() {
+		fabdns.Namespaces = mustParse("*")
+		fabdns.ExcludeNamespaces = mustParse("tenant in (a,b)")
+		Expect(fabdns.isNamespaceAllowed(namespaceTenant)).To(BeFalse())
+		Expect(fabdns.isNamespaceAllowed(namespaceDefault)).To(BeTrue())
+	})
+
+	It("should answer NXDOMAIN for normal and ad-hoc queries of excluded namespaces", func() {
+		fabdns.ExcludeNamespaces = mustParse("tenant-?")
+		for _, name := range []string{qname, adHocQName} {
+			executeTestCase(fabdns, testRecorder, test.Case{
+				Qname: name,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeNameError,
+			})
+		}
+	})
+
+	It("should answer queries of allowed namespaces", func() {
+		fabdns.Namespaces = mustParse("tenant=a")
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.11.1")),
+			},
+		})
+	})
+
+	It("should parse patterns and selectors", func() {
+		matchers := mustParse("tenant-*", "tenant=a", "tenant notin (b)")
+		Expect(matchers[0]).To(Equal(NamespaceMatcher{Pattern: "tenant-*"}))
+		Expect(matchers[1].Selector.String()).To(Equal("tenant=a"))
+		Expect(matchers[2].Selector.String()).To(Equal("tenant notin (b)"))
+
+		_, err := parseNamespaceMatchers([]string{"tenant-["})
+		Expect(err).To(HaveOccurred())
+
+		_, err = parseNamespaceMatchers(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
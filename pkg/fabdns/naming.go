@@ -0,0 +1,75 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// naming schemes of query names
+const (
+	// NamingFabEdge supports all formats of parseRequest, it's the default naming scheme
+	NamingFabEdge = "fabedge"
+	// NamingMCS only supports formats defined by Kubernetes Multi-Cluster Services DNS
+	// specification (KEP-1645), which are:
+	// {service}.{namespace}.svc.{zone}
+	// {hostname}.{cluster}.{service}.{namespace}.svc.{zone}
+	// and SRV queries of them
+	NamingMCS = "mcs"
+)
+
+func parseNaming(naming string) (string, error) {
+	switch naming {
+	case NamingFabEdge, NamingMCS:
+		return naming, nil
+	default:
+		return "", fmt.Errorf("unknown naming scheme '%s'", naming)
+	}
+}
+
+func (f FabDNS) isMCSNaming() bool {
+	return f.Naming == NamingMCS
+}
+
+// parseRequest parses name by the naming scheme of fabdns, ad-hoc and deprecated formats
+// are rejected if the naming scheme is mcs.
+func (f FabDNS) parseRequest(name, zone string) (recordRequest, error) {
+	r, err := parseRequest(name, zone)
+	if err != nil {
+		return r, err
+	}
+
+	if f.isMCSNaming() && r.isAdHoc {
+		return r, errInvalidRequest
+	}
+
+	return r, nil
+}
+
+// endpointName returns the domain name of endpoint by the naming scheme of fabdns.
+// Names of ad-hoc format don't exist in mcs naming scheme, endpoints without hostname
+// are named by their service instead.
+func (f FabDNS) endpointName(zone string, parsedReq recordRequest, globalService apis.GlobalService, endpoint apis.Endpoint) string {
+	if !f.isMCSNaming() {
+		return endpointName(zone, parsedReq, globalService, endpoint)
+	}
+
+	if globalService.Spec.Type == apis.Headless && endpoint.Hostname != nil {
+		return fmt.Sprintf("%s.%s.%s.%s.%s.%s", *endpoint.Hostname, endpoint.Cluster, parsedReq.service, parsedReq.namespace, LabelSVC, zone)
+	}
+	return fmt.Sprintf("%s.%s.%s.%s", parsedReq.service, parsedReq.namespace, LabelSVC, zone)
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Naming", func() {
+	var (
+		zone         = "clusterset.local."
+		svcMCS       = "mcs-nginx"
+		hostname     = "nginx-0"
+		qname        = fmt.Sprintf("%s.%s.svc.%s", svcMCS, namespaceDefault, zone)
+		hostQName    = fmt.Sprintf("%s.chaoyang.%s", hostname, qname)
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcMCS,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.Headless,
+				Ports: []apis.ServicePort{
+					{
+						Port:     80,
+						Name:     "web",
+						Protocol: corev1.ProtocolTCP,
+					},
+				},
+				Endpoints: []apis.Endpoint{
+					{
+						Hostname:  &hostname,
+						Cluster:   "chaoyang",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.3.1"},
+					},
+					{
+						Cluster:   "chaoyang",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.3.2"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{zone},
+			TTL:    5,
+			Client: testK8sClient,
+			Naming: NamingMCS,
+			Cluster: ClusterInfo{
+				Name:   "chaoyang",
+				Zone:   "beijing",
+				Region: "north",
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should answer service and hostname queries", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.3.1")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.3.2")),
+			},
+		})
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: hostQName,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", hostQName, "192.168.3.1")),
+			},
+		})
+	})
+
+	It("should use names of mcs naming scheme as SRV targets", func() {
+		srvName := "_web._tcp." + qname
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: srvName,
+			Qtype: dns.TypeSRV,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", srvName, qname)),
+				test.SRV(fmt.Sprintf("%s    5    IN    SRV    0 100 80 %s", srvName, hostQName)),
+			},
+			Extra: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.3.1")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, "192.168.3.2")),
+				test.A(fmt.Sprintf("%s    5    IN    A    %s", hostQName, "192.168.3.1")),
+			},
+		})
+	})
+
+	It("should return NXDOMAIN for ad-hoc and deprecated formats", func() {
+		for _, name := range []string{
+			fmt.Sprintf("chaoyang.%s", qname),
+			fmt.Sprintf("%s.%s.chaoyang.%s", svcMCS, namespaceDefault, zone),
+		} {
+			executeTestCase(fabdns, testRecorder, test.Case{
+				Qname: name,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeNameError,
+				Ns: []dns.RR{
+					test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", zone, zone, zone)),
+				},
+			})
+		}
+	})
+})
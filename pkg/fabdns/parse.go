@@ -15,10 +15,17 @@
 package fabdns
 
 import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/miekg/dns"
 )
 
 type recordRequest struct {
+	// The port name of a SRV query, e.g. http of _http._tcp
+	port string
+	// The protocol of a SRV query, e.g. tcp of _http._tcp
+	protocol string
 	// The hostname referring to individual pod backing a headless global service.
 	hostname string
 	// The clustername referring to identifiers between various clusters.
@@ -29,47 +36,73 @@ type recordRequest struct {
 	namespace string
 	// query svc in a specified cluster or not
 	isAdHoc bool
+	// query name is in the deprecated format or not
+	isDeprecated bool
 }
 
-func parseRequest(name string) (r recordRequest, err error) {
+// formats of query names
+const (
+	formNormal     = "normal"
+	formAdHoc      = "ad-hoc"
+	formHeadless   = "headless"
+	formDeprecated = "deprecated"
+	formReverse    = "reverse"
+)
+
+func parseRequest(name, zone string) (r recordRequest, err error) {
 	// support the following formats:
-	// {service}.{namespace}.svc.global
-	// {cluster}.{service}.{namespace}.svc.global
-	// {hostname}.{cluster}.{service}.{namespace}.svc.global
-	// {service}.{namespace}.{cluster}.global  deprecated, prefer {cluster}.{service}.{namespace}.svc.global
+	// {service}.{namespace}.svc.{zone}
+	// {cluster}.{service}.{namespace}.svc.{zone}
+	// {hostname}.{cluster}.{service}.{namespace}.svc.{zone}
+	// {service}.{namespace}.{cluster}.{zone}  deprecated, prefer {cluster}.{service}.{namespace}.svc.{zone}
+	// SRV queries may prefix the formats above except the hostname one with _{port}._{protocol}
+	// zone may have any number of labels, e.g. global or global.example.com
+
+	base, err := dnsutil.TrimZone(name, zone)
+	if err != nil {
+		return r, errInvalidRequest
+	}
+
+	labels := dns.SplitDomainName(base)
+	if len(labels) > 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+		r.port = strings.TrimPrefix(labels[0], "_")
+		r.protocol = strings.TrimPrefix(labels[1], "_")
+		labels = labels[2:]
+	}
 
-	labels := dns.SplitDomainName(name)
-	if len(labels) < 4 && len(labels) > 6 {
+	if len(labels) < 3 || len(labels) > 5 {
 		return r, errInvalidRequest
 	}
 
+	last := labels[len(labels)-1]
 	switch {
-	case len(labels) == 6:
-		// hostname.cluster.service.namespace.svc.global
+	case len(labels) == 5 && last == LabelSVC && r.port == "":
+		// hostname.cluster.service.namespace.svc
 		r.hostname = labels[0]
 		r.cluster = labels[1]
 		r.service = labels[2]
 		r.namespace = labels[3]
 		r.isAdHoc = false
-	case len(labels) == 5:
-		// cluster.service.namespace.svc.global
+	case len(labels) == 4 && last == LabelSVC:
+		// cluster.service.namespace.svc
 		r.cluster = labels[0]
 		r.service = labels[1]
 		r.namespace = labels[2]
 		r.isAdHoc = true
-	case len(labels) == 4 && labels[2] == LabelSVC:
-		// e.g. web.default.svc.global
+	case len(labels) == 3 && last == LabelSVC:
+		// e.g. web.default.svc
 		// If you name your cluster as "svc", it is your problem.
 		r.service = labels[0]
 		r.namespace = labels[1]
 		r.isAdHoc = false
-	case len(labels) == 4:
+	case len(labels) == 3:
 		// deprecated
-		// e.g. web.default.root.global
+		// e.g. web.default.root
 		r.service = labels[0]
 		r.namespace = labels[1]
 		r.cluster = labels[2]
 		r.isAdHoc = true
+		r.isDeprecated = true
 	default:
 		return r, errInvalidRequest
 	}
@@ -80,9 +113,30 @@ func parseRequest(name string) (r recordRequest, err error) {
 // String returns a string representation of r, it just returns all fields concatenated with dots.
 // This is mostly used in tests.
 func (r recordRequest) String() string {
-	s := r.hostname
+	s := r.port
+	s += "." + r.protocol
+	s += "." + r.hostname
 	s += "." + r.cluster
 	s += "." + r.service
 	s += "." + r.namespace
 	return s
 }
+
+// form returns the format of r, it's used as label value of metrics
+func (r recordRequest) form() string {
+	switch {
+	case r.isDeprecated:
+		return formDeprecated
+	case r.hostname != "":
+		return formHeadless
+	case r.isAdHoc:
+		return formAdHoc
+	default:
+		return formNormal
+	}
+}
+
+// isSRV returns true if r has _{port}._{protocol} prefix
+func (r recordRequest) isSRV() bool {
+	return r.port != "" || r.protocol != ""
+}
@@ -42,7 +42,7 @@ func testValidRequests() {
 					hostname:  "",
 				},
 			}
-			req, err := parseRequest(test.qname)
+			req, err := parseRequest(test.qname, testZone)
 			Expect(err).To(BeNil())
 			Expect(req).To(Equal(test.rr))
 		})
@@ -60,22 +60,81 @@ func testValidRequests() {
 				},
 			}
 
-			req, err := parseRequest(test.qname)
+			req, err := parseRequest(test.qname, testZone)
 			Expect(err).To(BeNil())
 			Expect(req).To(Equal(test.rr))
 		})
 	})
 
-	When("ad-hoc clusterIP svc request", func() {
-		It("should no error", func() {
+	When("SRV request", func() {
+		It("should be no error", func() {
 			tests := []testExpected{
-				{"myservice.mynamespace.mycluster." + testZone,
+				{"_http._tcp.myservice.mynamespace.svc." + testZone,
+					recordRequest{
+						port:      "http",
+						protocol:  "tcp",
+						service:   "myservice",
+						namespace: "mynamespace",
+						isAdHoc:   false,
+					},
+				},
+				{"_http._tcp.mycluster.myservice.mynamespace.svc." + testZone,
 					recordRequest{
+						port:      "http",
+						protocol:  "tcp",
 						service:   "myservice",
 						namespace: "mynamespace",
 						cluster:   "mycluster",
 						isAdHoc:   true,
-						hostname:  "",
+					},
+				},
+			}
+			for _, test := range tests {
+				req, err := parseRequest(test.qname, testZone)
+				Expect(err).To(BeNil())
+				Expect(req).To(Equal(test.rr))
+			}
+		})
+	})
+
+	When("zone has multiple labels", func() {
+		It("should be no error", func() {
+			zone := "clusterset.example.local."
+			tests := []testExpected{
+				{"myservice.mynamespace.svc." + zone,
+					recordRequest{
+						service:   "myservice",
+						namespace: "mynamespace",
+					},
+				},
+				{"hostname.mycluster.myservice.mynamespace.svc." + zone,
+					recordRequest{
+						hostname:  "hostname",
+						cluster:   "mycluster",
+						service:   "myservice",
+						namespace: "mynamespace",
+					},
+				},
+			}
+			for _, test := range tests {
+				req, err := parseRequest(test.qname, zone)
+				Expect(err).To(BeNil())
+				Expect(req).To(Equal(test.rr))
+			}
+		})
+	})
+
+	When("ad-hoc clusterIP svc request", func() {
+		It("should no error", func() {
+			tests := []testExpected{
+				{"myservice.mynamespace.mycluster." + testZone,
+					recordRequest{
+						service:      "myservice",
+						namespace:    "mynamespace",
+						cluster:      "mycluster",
+						isAdHoc:      true,
+						isDeprecated: true,
+						hostname:     "",
 					},
 				},
 				{"mycluster.myservice.mynamespace.svc." + testZone,
@@ -89,7 +148,7 @@ func testValidRequests() {
 				},
 			}
 			for _, test := range tests {
-				req, err := parseRequest(test.qname)
+				req, err := parseRequest(test.qname, testZone)
 				Expect(err).To(BeNil())
 				Expect(req).To(Equal(test.rr))
 			}
@@ -99,10 +158,33 @@ func testValidRequests() {
 
 func testInvalidRequests() {
 
+	When("request is not under the zone", func() {
+		It("should be error", func() {
+			_, err := parseRequest(testZone, testZone)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	When("svc label is not next to the zone", func() {
+		It("should be error", func() {
+			qname := "myservice.mynamespace.svc.mycluster.mynamespace." + testZone
+			_, err := parseRequest(qname, testZone)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	When("SRV request has hostname", func() {
+		It("should be error", func() {
+			qname := "_http._tcp.hostname.mycluster.myservice.mynamespace.svc." + testZone
+			_, err := parseRequest(qname, testZone)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
 	When("request too long", func() {
 		It("should be error", func() {
 			qname := "too.lang.request.myservice.mynamespace.svc." + testZone
-			_, err := parseRequest(qname)
+			_, err := parseRequest(qname, testZone)
 			Expect(err).Should(HaveOccurred())
 		})
 	})
@@ -110,7 +192,7 @@ func testInvalidRequests() {
 	When("request too short", func() {
 		It("should be error", func() {
 			qname := "mynamespace.svc." + testZone
-			_, err := parseRequest(qname)
+			_, err := parseRequest(qname, testZone)
 			Expect(err).Should(HaveOccurred())
 		})
 	})
@@ -118,7 +200,7 @@ func testInvalidRequests() {
 	When("request too short", func() {
 		It("should be error", func() {
 			qname := "svc." + testZone
-			_, err := parseRequest(qname)
+			_, err := parseRequest(qname, testZone)
 			Expect(err).Should(HaveOccurred())
 		})
 	})
@@ -0,0 +1,102 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// Policy is an ordered list of topology tiers, endpoints in the first tier
+// which can generate records are used to answer a query.
+type Policy []string
+
+const (
+	PolicyLocalOnly   = "local-only"
+	PolicyPreferLocal = "prefer-local"
+	PolicyZoneOnly    = "zone-only"
+	PolicyAny         = "any"
+)
+
+var policyByMode = map[string]Policy{
+	PolicyLocalOnly:   {tierCluster},
+	PolicyPreferLocal: {tierCluster, tierZone, tierRegion, tierAll},
+	PolicyZoneOnly:    {tierCluster, tierZone},
+	PolicyAny:         {tierAll},
+}
+
+// parsePolicy parses a policy from a mode name or an ordered list of tiers
+func parsePolicy(args []string) (Policy, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no policy mode or tiers specified")
+	}
+
+	if len(args) == 1 {
+		if policy, ok := policyByMode[args[0]]; ok {
+			return policy, nil
+		}
+	}
+
+	var policy Policy
+	seen := make(map[string]bool)
+	for _, tier := range args {
+		switch tier {
+		case tierCluster, tierZone, tierRegion, tierAll:
+		default:
+			return nil, fmt.Errorf("unknown policy mode or tier '%s'", tier)
+		}
+
+		if seen[tier] {
+			return nil, fmt.Errorf("duplicated policy tier '%s'", tier)
+		}
+		seen[tier] = true
+		policy = append(policy, tier)
+	}
+
+	return policy, nil
+}
+
+// policyOf returns the topology policy of global service, services without their own policy
+// use the default policy of fabdns, which is prefer-local if not configured.
+func (f FabDNS) policyOf(globalService apis.GlobalService) Policy {
+	if policy, ok := f.ServicePolicies[client.ObjectKeyFromObject(&globalService)]; ok {
+		return policy
+	}
+
+	if len(f.Policy) > 0 {
+		return f.Policy
+	}
+
+	return policyByMode[PolicyPreferLocal]
+}
+
+// inTier returns true if endpoint is located in the tier relative to local cluster
+func (f FabDNS) inTier(endpoint apis.Endpoint, tier string) bool {
+	switch tier {
+	case tierCluster:
+		return endpoint.Cluster == f.Cluster.Name
+	case tierZone:
+		return endpoint.Zone == f.Cluster.Zone
+	case tierRegion:
+		return endpoint.Region == f.Cluster.Region
+	case tierAll:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Policy", func() {
+	Context("Parsing policies", testParsePolicy)
+	Context("Selecting endpoints by policies", testSelectEndpointsByPolicy)
+})
+
+func testParsePolicy() {
+	It("should parse policy modes", func() {
+		for mode, expected := range map[string]Policy{
+			PolicyLocalOnly:   {tierCluster},
+			PolicyPreferLocal: {tierCluster, tierZone, tierRegion, tierAll},
+			PolicyZoneOnly:    {tierCluster, tierZone},
+			PolicyAny:         {tierAll},
+		} {
+			policy, err := parsePolicy([]string{mode})
+			Expect(err).To(Succeed())
+			Expect(policy).To(Equal(expected))
+		}
+	})
+
+	It("should parse ordered tiers", func() {
+		policy, err := parsePolicy([]string{"zone", "cluster"})
+		Expect(err).To(Succeed())
+		Expect(policy).To(Equal(Policy{tierZone, tierCluster}))
+	})
+
+	It("should fail if tiers are invalid", func() {
+		for _, args := range [][]string{nil, {"zone", "zone"}, {"local-only", "zone"}, {"planet"}} {
+			_, err := parsePolicy(args)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+}
+
+func testSelectEndpointsByPolicy() {
+	var (
+		svcPolicyNginx = "policy-nginx"
+		qname          = fmt.Sprintf("%s.%s.svc.%s", svcPolicyNginx, namespaceDefault, testZone)
+		testService    apis.GlobalService
+		testRecorder   *dnstest.Recorder
+		fabdns         *FabDNS
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcPolicyNginx,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   "chaoyang",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.1.1"},
+					},
+					{
+						Cluster:   "xicheng",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"192.168.1.2"},
+					},
+					{
+						Cluster:   "minhang",
+						Region:    "south",
+						Zone:      "shanghai",
+						Addresses: []string{"192.168.1.3"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   "chaoyang",
+				Zone:   "beijing",
+				Region: "north",
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	aRecords := func(addresses ...string) []dns.RR {
+		var records []dns.RR
+		for _, addr := range addresses {
+			records = append(records, test.A(fmt.Sprintf("%s    5    IN    A    %s", qname, addr)))
+		}
+		return records
+	}
+
+	It("should answer all endpoints with any policy", func() {
+		fabdns.Policy = Policy{tierAll}
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: aRecords("192.168.1.1", "192.168.1.2", "192.168.1.3"),
+		})
+	})
+
+	It("should answer endpoints of the first tier which has records", func() {
+		fabdns.Policy = Policy{tierZone, tierCluster}
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: aRecords("192.168.1.1", "192.168.1.2"),
+		})
+	})
+
+	It("should answer NODATA if no tier of local-only policy has records", func() {
+		fabdns.Cluster.Name = "haidian"
+		fabdns.Policy = Policy{tierCluster}
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: qname,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Ns: []dns.RR{
+				test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone)),
+			},
+		})
+	})
+
+	It("should answer endpoints of the cluster picked by weight in the selected tier", func() {
+		weight, drained := int32(100), int32(0)
+		testService.Spec.Endpoints[0].Weight = &drained
+		testService.Spec.Endpoints[1].Weight = &weight
+		updateGlobalService(testK8sClient, &testService)
+
+		fabdns.Policy = Policy{tierZone}
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: aRecords("192.168.1.2"),
+		})
+	})
+
+	It("should prefer policy of the service to the default policy", func() {
+		fabdns.Policy = Policy{tierCluster}
+		fabdns.ServicePolicies = map[client.ObjectKey]Policy{
+			{Namespace: namespaceDefault, Name: svcPolicyNginx}: {tierAll},
+		}
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: aRecords("192.168.1.1", "192.168.1.2", "192.168.1.3"),
+		})
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// indexAddress is the name of index which maps endpoint addresses to global services
+const indexAddress = "spec.endpoints.addresses"
+
+// addressIndexFunc returns the normalized addresses of all endpoints of a global service
+func addressIndexFunc(obj client.Object) []string {
+	globalService, ok := obj.(*apis.GlobalService)
+	if !ok {
+		return nil
+	}
+
+	var addresses []string
+	for _, endpoint := range globalService.Spec.Endpoints {
+		for _, addr := range endpoint.Addresses {
+			if ip, ok := verifyIP(addr); ok {
+				addresses = append(addresses, ip.String())
+			}
+		}
+	}
+
+	return addresses
+}
+
+// getPTRRecords returns PTR records of the address in reverse query name, the address is
+// looked up by address index, so Client has to be a cache which has the index.
+func (f FabDNS) getPTRRecords(state *request.Request) ([]dns.RR, error) {
+	address := dnsutil.ExtractAddressFromReverse(strings.ToLower(state.QName()))
+	if address == "" {
+		log.Debugf("failed to extract address from %s", state.QName())
+		return nil, errInvalidRequest
+	}
+
+	// addresses synthesized by DNS64 are answered as the IPv4 addresses embedded in them
+	if ipv4 := f.embeddedIPv4(net.ParseIP(address)); ipv4 != nil {
+		address = ipv4.String()
+	}
+
+	zone := f.primaryZone()
+	if zone == "" {
+		log.Debugf("no forward zone is configured for PTR records")
+		return nil, errNoItems
+	}
+
+	var globalServices apis.GlobalServiceList
+	err := f.listObjects(&globalServices, client.MatchingFields{indexAddress: address})
+	if err != nil {
+		log.Errorf("failed to list GlobalServices by address %s: %v", address, err)
+		return nil, err
+	}
+
+	var records []dns.RR
+	for _, globalService := range globalServices.Items {
+		if !f.isNamespaceAllowed(globalService.Namespace) {
+			continue
+		}
+
+		parsedReq := recordRequest{
+			service:   globalService.Name,
+			namespace: globalService.Namespace,
+			isAdHoc:   true,
+		}
+
+		for _, endpoint := range globalService.Spec.Endpoints {
+			if !hasAddress(endpoint, address) {
+				continue
+			}
+
+			records = append(records, &dns.PTR{
+				Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypePTR, Class: state.QClass(), Ttl: f.ttlOf(globalService)},
+				Ptr: f.endpointName(zone, parsedReq, globalService, endpoint),
+			})
+		}
+	}
+
+	if len(records) == 0 {
+		log.Debugf("no endpoint found by address %s", address)
+		return nil, errNoItems
+	}
+
+	return dns.Dedup(records, nil), nil
+}
+
+// primaryZone returns the first zone which is not a reverse zone
+func (f FabDNS) primaryZone() string {
+	for _, zone := range f.Zones {
+		if !dns.IsSubDomain("arpa.", zone) {
+			return zone
+		}
+	}
+	return ""
+}
+
+func hasAddress(endpoint apis.Endpoint, address string) bool {
+	for _, addr := range endpoint.Addresses {
+		if ip, ok := verifyIP(addr); ok && ip.Equal(net.ParseIP(address)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("PTR", func() {
+	var (
+		svcPTRNginx  = "ptr-nginx"
+		svcPTRMysql  = "ptr-mysql"
+		hostname     = "mysql-0"
+		nginxService apis.GlobalService
+		mysqlService apis.GlobalService
+
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	BeforeEach(func() {
+		nginxService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcPTRNginx,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   "xicheng",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"10.10.0.1", "FD00::1"},
+					},
+				},
+			},
+		}
+		mysqlService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcPTRMysql,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.Headless,
+				Endpoints: []apis.Endpoint{
+					{
+						Hostname:  &hostname,
+						Cluster:   "chaoyang",
+						Region:    "north",
+						Zone:      "beijing",
+						Addresses: []string{"10.10.1.1"},
+					},
+					{
+						Cluster:   "minhang",
+						Region:    "south",
+						Zone:      "shanghai",
+						Addresses: []string{"10.10.1.2"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &nginxService)
+		createGlobalService(testK8sClient, &mysqlService)
+		expectCachedGlobalService(&nginxService)
+		expectCachedGlobalService(&mysqlService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone, "in-addr.arpa.", "ip6.arpa."},
+			TTL:    5,
+			Client: testCache,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &nginxService)
+		deleteGlobalService(testK8sClient, &mysqlService)
+	})
+
+	It("should answer cluster specific name for ClusterIP endpoint addresses", func() {
+		for _, address := range []string{"10.10.0.1", "fd00::1"} {
+			qname, err := dns.ReverseAddr(address)
+			Expect(err).To(Succeed())
+
+			testCase := test.Case{
+				Qname: qname,
+				Qtype: dns.TypePTR,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.PTR(fmt.Sprintf("%s    5    IN    PTR    xicheng.%s.%s.svc.%s", qname, svcPTRNginx, namespaceDefault, testZone)),
+				},
+			}
+			executeTestCase(fabdns, testRecorder, testCase)
+		}
+	})
+
+	It("should answer addresses synthesized by DNS64 as their IPv4 addresses", func() {
+		fabdns.DNS64Prefix, _ = parseDNS64(nil)
+
+		qname, err := dns.ReverseAddr("64:ff9b::a0a:1")
+		Expect(err).To(Succeed())
+
+		testCase := test.Case{
+			Qname: qname,
+			Qtype: dns.TypePTR,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.PTR(fmt.Sprintf("%s    5    IN    PTR    xicheng.%s.%s.svc.%s", qname, svcPTRNginx, namespaceDefault, testZone)),
+			},
+		}
+		executeTestCase(fabdns, testRecorder, testCase)
+	})
+
+	It("should answer hostname for Headless endpoint addresses", func() {
+		testCase := test.Case{
+			Qname: "1.1.10.10.in-addr.arpa.",
+			Qtype: dns.TypePTR,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.PTR(fmt.Sprintf("1.1.10.10.in-addr.arpa.    5    IN    PTR    %s.chaoyang.%s.%s.svc.%s", hostname, svcPTRMysql, namespaceDefault, testZone)),
+			},
+		}
+		executeTestCase(fabdns, testRecorder, testCase)
+	})
+
+	It("should answer cluster specific name for Headless endpoint addresses without hostname", func() {
+		testCase := test.Case{
+			Qname: "2.1.10.10.in-addr.arpa.",
+			Qtype: dns.TypePTR,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.PTR(fmt.Sprintf("2.1.10.10.in-addr.arpa.    5    IN    PTR    minhang.%s.%s.svc.%s", svcPTRMysql, namespaceDefault, testZone)),
+			},
+		}
+		executeTestCase(fabdns, testRecorder, testCase)
+	})
+
+	It("should failed if address is not found", func() {
+		testCase := test.Case{
+			Qname: "3.1.10.10.in-addr.arpa.",
+			Qtype: dns.TypePTR,
+			Rcode: dns.RcodeNameError,
+		}
+		executeTestCase(fabdns, testRecorder, testCase)
+	})
+})
+
+func expectCachedGlobalService(globalService *apis.GlobalService) {
+	EventuallyWithOffset(1, func() error {
+		var gs apis.GlobalService
+		return testCache.Get(context.TODO(), client.ObjectKeyFromObject(globalService), &gs)
+	}).Should(Succeed())
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin/ready"
+)
+
+// unreachableGracePeriod is how long fabdns stays ready after API server is unreachable,
+// it avoids flapping caused by short interruptions
+const unreachableGracePeriod = 30 * time.Second
+
+var _ ready.Readiness = FabDNS{}
+
+// Ready implements ready.Readiness interface, fabdns is ready after the initial sync
+// of global service cache is done and API server is reachable recently.
+func (f FabDNS) Ready() bool {
+	if !f.isCacheSynced() {
+		return false
+	}
+
+	if f.monitor == nil {
+		return true
+	}
+
+	unreachable, ok := f.monitor.unreachableFor()
+	return !ok || unreachable <= f.maxUnreachable()
+}
+
+// maxUnreachable returns how long API server can be unreachable before fabdns is not ready,
+// serve_stale lengthens it to max staleness
+func (f FabDNS) maxUnreachable() time.Duration {
+	if f.ServeStale != nil && f.ServeStale.MaxStaleness > unreachableGracePeriod {
+		return f.ServeStale.MaxStaleness
+	}
+	return unreachableGracePeriod
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ready", func() {
+	var (
+		fabdns   *FabDNS
+		probeErr error
+	)
+
+	BeforeEach(func() {
+		probeErr = nil
+		fabdns = &FabDNS{
+			cacheSynced: make(chan struct{}),
+			monitor: &apiServerMonitor{
+				probe: func(ctx context.Context) error {
+					return probeErr
+				},
+				lastContact: time.Now(),
+			},
+		}
+	})
+
+	It("should not be ready until cache is synced", func() {
+		Expect(fabdns.Ready()).To(BeFalse())
+
+		fabdns.markCacheSynced()
+		Expect(fabdns.Ready()).To(BeTrue())
+	})
+
+	It("should be ready if API server is unreachable shortly", func() {
+		fabdns.markCacheSynced()
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+
+		Expect(fabdns.Ready()).To(BeTrue())
+	})
+
+	It("should not be ready if API server is unreachable longer than grace period", func() {
+		fabdns.markCacheSynced()
+		fabdns.monitor.lastContact = time.Now().Add(-time.Minute)
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+		Expect(fabdns.Ready()).To(BeFalse())
+
+		probeErr = nil
+		fabdns.monitor.probeOnce(context.TODO())
+		Expect(fabdns.Ready()).To(BeTrue())
+	})
+
+	It("should be ready until max staleness if serve_stale is configured", func() {
+		fabdns.markCacheSynced()
+		fabdns.ServeStale = &ServeStale{MaxStaleness: time.Hour}
+		fabdns.monitor.lastContact = time.Now().Add(-time.Minute)
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+		Expect(fabdns.Ready()).To(BeTrue())
+
+		fabdns.ServeStale.MaxStaleness = 10 * time.Second
+		Expect(fabdns.Ready()).To(BeFalse())
+
+		probeErr = nil
+		fabdns.monitor.probeOnce(context.TODO())
+		Expect(fabdns.Ready()).To(BeTrue())
+	})
+
+	It("should be ready if global services are read from manifests", func() {
+		Expect((&FabDNS{}).Ready()).To(BeTrue())
+	})
+})
@@ -15,12 +15,19 @@
 package fabdns
 
 import (
+	"fmt"
+	"net"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -53,6 +60,17 @@ func setup(c *caddy.Controller) error {
 		return fabdns
 	})
 
+	c.OnStartup(func() error {
+		// NOTIFY is sent by transfer plugin if it's configured
+		if t, ok := dnsserver.GetConfig(c).Handler("transfer").(*transfer.Transfer); ok {
+			fabdns.transfer = t
+			fabdns.nameserverIPs = listenIPs(dnsserver.GetConfig(c).ListenHosts)
+		}
+		return nil
+	})
+	c.OnStartup(fabdns.startCache)
+	c.OnShutdown(fabdns.shutdownCache)
+
 	return nil
 }
 
@@ -62,18 +80,43 @@ func fabdnsParse(c *caddy.Controller) (*FabDNS, error) {
 
 	zones := plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
 	var (
-		ttl           int = -1
-		fabFall       fall.F
-		masterurl     string
-		kubeconfig    string
-		cluster       string
-		clusterZone   string
-		clusterRegion string
+		ttl             int = -1
+		minServiceTTL   uint32
+		maxServiceTTL   uint32 = maxTTL
+		dns64Prefix     *net.IPNet
+		fabFall         fall.F
+		masterurl       string
+		kubeconfig      string
+		cluster         string
+		clusterZone     string
+		clusterRegion   string
+		naming          = NamingFabEdge
+		hostCollision   string
+		namespaces      []NamespaceMatcher
+		excludeNs       []NamespaceMatcher
+		manifestsDir    string
+		manifestsReload time.Duration
+		serveStale      *ServeStale
+		healthCheck     *HealthCheck
+		preferLatency   bool
+		latencyTol      time.Duration
+		clientLocations []ClientLocation
+		order           = OrderNone
+		maxAnswers      int
+		publishNotReady bool
+		policy          Policy
+		servicePolicies = make(map[client.ObjectKey]Policy)
 	)
 	for c.NextBlock() {
 		switch c.Val() {
 		case "fallthrough":
 			fabFall.SetZonesFromArgs(c.RemainingArgs())
+		case "manifests":
+			var err error
+			manifestsDir, manifestsReload, err = parseManifests(c.RemainingArgs())
+			if err != nil {
+				return nil, c.Errf("manifests %v", err)
+			}
 		case "kubeconfig":
 			args := c.RemainingArgs()
 			if len(args) != 1 {
@@ -104,6 +147,125 @@ func fabdnsParse(c *caddy.Controller) (*FabDNS, error) {
 				return nil, c.ArgErr()
 			}
 			clusterRegion = args[0]
+		case "client_cidr":
+			location, err := parseClientLocation(c.RemainingArgs())
+			if err != nil {
+				return nil, c.Errf("client_cidr %v", err)
+			}
+			clientLocations = append(clientLocations, location)
+		case "order":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			order, err = parseOrder(args[0])
+			if err != nil {
+				return nil, c.Errf("order %v", err)
+			}
+		case "max_answers":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			maxAnswers, err = strconv.Atoi(args[0])
+			if err != nil {
+				return nil, c.Errf("max_answers %v", err)
+			}
+			if maxAnswers < 0 {
+				return nil, c.Errf("max_answers %d must not be negative", maxAnswers)
+			}
+		case "publish_not_ready_addresses":
+			if len(c.RemainingArgs()) != 0 {
+				return nil, c.ArgErr()
+			}
+			publishNotReady = true
+		case "naming":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			naming, err = parseNaming(args[0])
+			if err != nil {
+				return nil, c.Errf("naming %v", err)
+			}
+		case "cross_cluster_hostname":
+			args := c.RemainingArgs()
+			if len(args) > 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			hostCollision, err = parseHostnameCollision(args)
+			if err != nil {
+				return nil, c.Errf("cross_cluster_hostname %v", err)
+			}
+		case "namespaces":
+			matchers, err := parseNamespaceMatchers(c.RemainingArgs())
+			if err != nil {
+				return nil, c.Errf("namespaces %v", err)
+			}
+			namespaces = append(namespaces, matchers...)
+		case "exclude_namespaces":
+			matchers, err := parseNamespaceMatchers(c.RemainingArgs())
+			if err != nil {
+				return nil, c.Errf("exclude_namespaces %v", err)
+			}
+			excludeNs = append(excludeNs, matchers...)
+		case "policy":
+			args := c.RemainingArgs()
+			if len(args) > 0 && strings.Contains(args[0], "/") {
+				// policy of a specified global service, e.g. policy default/nginx any
+				key, err := parseObjectKey(args[0])
+				if err != nil {
+					return nil, c.Errf("policy %v", err)
+				}
+
+				servicePolicy, err := parsePolicy(args[1:])
+				if err != nil {
+					return nil, c.Errf("policy %v", err)
+				}
+				servicePolicies[key] = servicePolicy
+				continue
+			}
+
+			var err error
+			policy, err = parsePolicy(args)
+			if err != nil {
+				return nil, c.Errf("policy %v", err)
+			}
+		case "serve_stale":
+			args := c.RemainingArgs()
+			if len(args) > 2 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			serveStale, err = parseServeStale(args)
+			if err != nil {
+				return nil, c.Errf("serve_stale %v", err)
+			}
+		case "health_check":
+			args := c.RemainingArgs()
+			if len(args) > 3 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			healthCheck, err = parseHealthCheck(args)
+			if err != nil {
+				return nil, c.Errf("health_check %v", err)
+			}
+		case "prefer_low_latency":
+			args := c.RemainingArgs()
+			if len(args) > 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			latencyTol, err = parseLatencyTolerance(args)
+			if err != nil {
+				return nil, c.Errf("prefer_low_latency %v", err)
+			}
+			preferLatency = true
 		case "ttl":
 			args := c.RemainingArgs()
 			if len(args) != 1 {
@@ -114,8 +276,24 @@ func fabdnsParse(c *caddy.Controller) (*FabDNS, error) {
 			if err != nil {
 				return nil, c.Errf("ttl %v", err)
 			}
-			if ttl < 0 || ttl > 3600 {
-				return nil, c.Errf("ttl %d is out of range [0, 3600], default ttl is %d if not configured", ttl, defaultTTL)
+			if ttl < 0 || ttl > maxTTL {
+				return nil, c.Errf("ttl %d is out of range [0, %d], default ttl is %d if not configured", ttl, maxTTL, defaultTTL)
+			}
+		case "service_ttl":
+			var err error
+			minServiceTTL, maxServiceTTL, err = parseServiceTTL(c.RemainingArgs())
+			if err != nil {
+				return nil, c.Errf("service_ttl %v", err)
+			}
+		case "dns64":
+			args := c.RemainingArgs()
+			if len(args) > 1 {
+				return nil, c.ArgErr()
+			}
+			var err error
+			dns64Prefix, err = parseDNS64(args)
+			if err != nil {
+				return nil, c.Errf("dns64 %v", err)
 			}
 		default:
 			return nil, c.Errf("unknown property '%s'", c.Val())
@@ -125,27 +303,121 @@ func fabdnsParse(c *caddy.Controller) (*FabDNS, error) {
 		ttl = defaultTTL
 	}
 
+	// latency is measured by health check probes
+	if preferLatency && healthCheck == nil {
+		return nil, c.Err("prefer_low_latency requires health_check")
+	}
+
+	fabdns := &FabDNS{
+		Zones:             zones,
+		Fall:              fabFall,
+		TTL:               uint32(ttl),
+		MinServiceTTL:     minServiceTTL,
+		MaxServiceTTL:     maxServiceTTL,
+		Naming:            naming,
+		HostnameCollision: hostCollision,
+		Namespaces:        namespaces,
+		Policy:            policy,
+		ServicePolicies:   servicePolicies,
+		ServeStale:        serveStale,
+		HealthCheck:       healthCheck,
+		ClientLocations:   clientLocations,
+		Order:             order,
+		MaxAnswers:        maxAnswers,
+		Upstream:          upstream.New(),
+		DNS64Prefix:       dns64Prefix,
+		rotation:          new(uint32),
+		zoneSerial:        newZoneSerial(),
+		Cluster: ClusterInfo{
+			Name:   cluster,
+			Zone:   clusterZone,
+			Region: clusterRegion,
+		},
+		PublishNotReadyAddresses: publishNotReady,
+		PreferLowLatency:         preferLatency,
+		ExcludeNamespaces:        excludeNs,
+		LatencyTolerance:         latencyTol,
+	}
+
+	// global services are read from manifests, API server is not needed
+	if manifestsDir != "" {
+		if serveStale != nil {
+			return nil, c.Err("serve_stale can't be used with manifests")
+		}
+
+		store, err := newFileStore(manifestsDir, manifestsReload)
+		if err != nil {
+			return nil, c.Errf("manifests %v", err)
+		}
+		store.onChange = fabdns.zoneSerial.touch
+		fabdns.Client, fabdns.store = store, store
+
+		return fabdns, nil
+	}
+
 	cfg, err := buildConfigFromFlags(masterurl, kubeconfig)
 	if err != nil {
 		return nil, err
 	}
 
-	cli, err := client.New(cfg, client.Options{})
+	globalServiceCache, err := newGlobalServiceCache(cfg, hasNamespaceSelector(namespaces, excludeNs))
 	if err != nil {
 		return nil, err
 	}
+	fabdns.Client, fabdns.cache = globalServiceCache, globalServiceCache
 
-	fabdns := &FabDNS{
-		Zones:  zones,
-		Fall:   fabFall,
-		TTL:    uint32(ttl),
-		Client: cli,
-		Cluster: ClusterInfo{
-			Name:   cluster,
-			Zone:   clusterZone,
-			Region: clusterRegion,
-		},
+	watchedObjects := []client.Object{&apis.GlobalService{}}
+	if hasNamespaceSelector(namespaces, excludeNs) {
+		watchedObjects = append(watchedObjects, &corev1.Namespace{})
+	}
+	if err = fabdns.zoneSerial.watch(globalServiceCache, watchedObjects...); err != nil {
+		return nil, err
+	}
+	fabdns.cacheSynced = make(chan struct{})
+
+	if fabdns.monitor, err = newAPIServerMonitor(cfg); err != nil {
+		return nil, err
 	}
 
 	return fabdns, nil
 }
+
+// parseObjectKey parses object key from string in the format of namespace/name
+func parseObjectKey(value string) (client.ObjectKey, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return client.ObjectKey{}, fmt.Errorf("invalid service key '%s', expected namespace/name", value)
+	}
+
+	return client.ObjectKey{Namespace: parts[0], Name: parts[1]}, nil
+}
+
+// parseServeStale parses serve_stale arguments in the format of [MAX_STALENESS [TTL]]
+func parseServeStale(args []string) (*ServeStale, error) {
+	serveStale := &ServeStale{MaxStaleness: defaultMaxStaleness}
+
+	if len(args) > 0 {
+		maxStaleness, err := time.ParseDuration(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if maxStaleness <= 0 {
+			return nil, fmt.Errorf("max staleness %s must be positive", args[0])
+		}
+		serveStale.MaxStaleness = maxStaleness
+	}
+
+	if len(args) > 1 {
+		ttl, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if ttl < 0 || ttl > maxTTL {
+			return nil, fmt.Errorf("ttl %d is out of range [0, %d]", ttl, maxTTL)
+		}
+		staleTTL := uint32(ttl)
+		serveStale.TTL = &staleTTL
+	}
+
+	return serveStale, nil
+}
@@ -16,6 +16,11 @@ package fabdns
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -24,6 +29,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type fakeHandler struct{}
@@ -57,6 +63,17 @@ func testCorrectConfig() {
 		})
 		It("should succeed with empty zones", func() {
 			Expect(fabdns.Zones).To(BeEmpty())
+			Expect(fabdns.Naming).To(Equal(NamingFabEdge))
+		})
+
+		It("should read global services from informer cache", func() {
+			Expect(fabdns.cache).NotTo(BeNil())
+			Expect(fabdns.Client).To(BeIdenticalTo(fabdns.cache))
+		})
+
+		It("should not be ready before cache is synced", func() {
+			Expect(fabdns.monitor).NotTo(BeNil())
+			Expect(fabdns.Ready()).To(BeFalse())
 		})
 	})
 
@@ -120,6 +137,39 @@ func testCorrectConfig() {
 		})
 	})
 
+	When("fabdns manifests is specified", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "fabdns-manifests")
+			Expect(err).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "services.yaml"), []byte(testManifests), 0644)).To(Succeed())
+
+			config = fmt.Sprintf(`fabdns {
+				manifests %s 10s
+			}`, dir)
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+		It("should read global services from manifests", func() {
+			Expect(fabdns.cache).To(BeNil())
+			Expect(fabdns.store).NotTo(BeNil())
+			Expect(fabdns.Client).To(BeIdenticalTo(fabdns.store))
+			Expect(fabdns.store.reload).To(Equal(10 * time.Second))
+			Expect(fabdns.store.globalServices).To(HaveLen(1))
+		})
+
+		It("should bump serial of zones after manifests are reloaded", func() {
+			serial := fabdns.serial()
+			Expect(fabdns.serial()).To(Equal(serial))
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "mysql.json"), []byte(testJSONManifest), 0644)).To(Succeed())
+			Expect(fabdns.store.load()).To(Succeed())
+			Expect(fabdns.serial()).To(BeNumerically(">", serial))
+		})
+	})
+
 	When("fabdns cluster location infos are specified", func() {
 		BeforeEach(func() {
 			config = `fabdns {
@@ -135,6 +185,151 @@ func testCorrectConfig() {
 		})
 	})
 
+	When("fabdns policy mode is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				policy local-only
+				policy default/nginx any
+				policy default/mysql zone region
+			}`
+		})
+		It("should succeed with the specified policies", func() {
+			Expect(fabdns.Policy).To(Equal(Policy{tierCluster}))
+			Expect(fabdns.ServicePolicies).To(Equal(map[client.ObjectKey]Policy{
+				{Namespace: "default", Name: "nginx"}: {tierAll},
+				{Namespace: "default", Name: "mysql"}: {tierZone, tierRegion},
+			}))
+		})
+	})
+
+	When("fabdns naming is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns clusterset.local {
+				naming mcs
+			}`
+		})
+		It("should succeed with the specified naming scheme", func() {
+			Expect(fabdns.Naming).To(Equal(NamingMCS))
+		})
+	})
+
+	When("fabdns cross_cluster_hostname is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				cross_cluster_hostname nearest
+			}`
+		})
+		It("should succeed with the specified hostname collision policy", func() {
+			Expect(fabdns.HostnameCollision).To(Equal(HostnameCollisionNearest))
+		})
+	})
+
+	When("fabdns serve_stale is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				serve_stale 30m 10
+			}`
+		})
+		It("should succeed with the specified max staleness and ttl", func() {
+			Expect(fabdns.ServeStale.MaxStaleness).To(Equal(30 * time.Minute))
+			Expect(*fabdns.ServeStale.TTL).To(Equal(uint32(10)))
+			Expect(fabdns.monitor).NotTo(BeNil())
+		})
+	})
+
+	When("fabdns serve_stale is specified without arguments", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				serve_stale
+			}`
+		})
+		It("should succeed with the default max staleness", func() {
+			Expect(fabdns.ServeStale.MaxStaleness).To(Equal(defaultMaxStaleness))
+			Expect(fabdns.ServeStale.TTL).To(BeNil())
+		})
+	})
+
+	When("fabdns health_check is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				health_check 30s 5
+			}`
+		})
+		It("should succeed with the specified interval and failures", func() {
+			Expect(fabdns.HealthCheck.Interval).To(Equal(30 * time.Second))
+			Expect(fabdns.HealthCheck.Failures).To(Equal(5))
+			Expect(fabdns.HealthCheck.Timeout).To(Equal(defaultHealthCheckTimeout))
+		})
+	})
+
+	When("fabdns prefer_low_latency is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				health_check
+				prefer_low_latency 20ms
+			}`
+		})
+		It("should succeed with the specified latency tolerance", func() {
+			Expect(fabdns.PreferLowLatency).To(BeTrue())
+			Expect(fabdns.LatencyTolerance).To(Equal(20 * time.Millisecond))
+		})
+	})
+
+	When("fabdns namespaces and exclude_namespaces are specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				namespaces tenant-* "tenant in (a,b)"
+				exclude_namespaces kube-system
+			}`
+		})
+		It("should succeed with the specified namespace matchers", func() {
+			Expect(fabdns.Namespaces).To(HaveLen(2))
+			Expect(fabdns.Namespaces[0].Pattern).To(Equal("tenant-*"))
+			Expect(fabdns.Namespaces[1].Selector.String()).To(Equal("tenant in (a,b)"))
+			Expect(fabdns.ExcludeNamespaces).To(Equal([]NamespaceMatcher{{Pattern: "kube-system"}}))
+		})
+	})
+
+	When("fabdns client_cidr is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				client_cidr 10.20.0.0/16 chaoyang beijing north
+				client_cidr fd00::/64 minhang shanghai south
+			}`
+		})
+		It("should succeed with the specified client locations", func() {
+			Expect(fabdns.ClientLocations).To(HaveLen(2))
+			Expect(fabdns.ClientLocations[0].CIDR.String()).To(Equal("10.20.0.0/16"))
+			Expect(fabdns.ClientLocations[0].Cluster).To(Equal(ClusterInfo{Name: "chaoyang", Zone: "beijing", Region: "north"}))
+			Expect(fabdns.ClientLocations[1].CIDR.String()).To(Equal("fd00::/64"))
+		})
+	})
+
+	When("fabdns order and max_answers are specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				order round_robin
+				max_answers 8
+			}`
+		})
+		It("should succeed with the specified order and max answers", func() {
+			Expect(fabdns.Order).To(Equal(OrderRoundRobin))
+			Expect(fabdns.MaxAnswers).To(Equal(8))
+			Expect(fabdns.rotation).NotTo(BeNil())
+		})
+	})
+
+	When("fabdns publish_not_ready_addresses is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				publish_not_ready_addresses
+			}`
+		})
+		It("should succeed with publishing not ready addresses", func() {
+			Expect(fabdns.PublishNotReadyAddresses).To(BeTrue())
+		})
+	})
+
 	When("fabdns ttl is specified", func() {
 		BeforeEach(func() {
 			config = `fabdns {
@@ -143,6 +338,31 @@ func testCorrectConfig() {
 		})
 		It("should succeed with the specified ttl", func() {
 			Expect(fabdns.TTL).To(Equal(uint32(30)))
+			Expect(fabdns.MinServiceTTL).To(Equal(uint32(0)))
+			Expect(fabdns.MaxServiceTTL).To(Equal(uint32(maxTTL)))
+		})
+	})
+
+	When("fabdns service_ttl is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				service_ttl 10 600
+			}`
+		})
+		It("should succeed with the specified bounds of service ttl", func() {
+			Expect(fabdns.MinServiceTTL).To(Equal(uint32(10)))
+			Expect(fabdns.MaxServiceTTL).To(Equal(uint32(600)))
+		})
+	})
+
+	When("fabdns dns64 is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				dns64 2001:db8:64::/96
+			}`
+		})
+		It("should succeed with the specified nat64 prefix", func() {
+			Expect(fabdns.DNS64Prefix.String()).To(Equal("2001:db8:64::/96"))
 		})
 	})
 }
@@ -179,6 +399,161 @@ func testIncorrectConfig() {
 		})
 	})
 
+	When("unknown policy is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				policy nearest
+			}`
+		})
+		It("should return policy error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("unknown policy mode or tier"))
+		})
+	})
+
+	When("policy of service has invalid key", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				policy default/nginx/web any
+			}`
+		})
+		It("should return policy error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("invalid service key"))
+		})
+	})
+
+	When("unknown hostname collision policy is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				cross_cluster_hostname first
+			}`
+		})
+		It("should return cross_cluster_hostname error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("unknown hostname collision policy"))
+		})
+	})
+
+	When("unknown naming is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				naming kubernetes
+			}`
+		})
+		It("should return naming error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("unknown naming scheme"))
+		})
+	})
+
+	When("invalid max staleness is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				serve_stale -1h
+			}`
+		})
+		It("should return serve_stale error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("serve_stale"))
+		})
+	})
+
+	When("invalid health check interval is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				health_check 0s
+			}`
+		})
+		It("should return health_check error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("health_check"))
+		})
+	})
+
+	When("invalid nat64 prefix is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				dns64 64:ff9b::/80
+			}`
+		})
+		It("should return dns64 error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("dns64"))
+		})
+	})
+
+	When("prefer_low_latency is specified without health_check", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				prefer_low_latency
+			}`
+		})
+		It("should return prefer_low_latency error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("requires health_check"))
+		})
+	})
+
+	When("invalid namespace selector is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				exclude_namespaces "tenant in a"
+			}`
+		})
+		It("should return exclude_namespaces error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("exclude_namespaces"))
+		})
+	})
+
+	When("manifests directory doesn't exist", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				manifests /not/exist
+			}`
+		})
+		It("should return manifests error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("manifests"))
+		})
+	})
+
+	When("serve_stale is specified with manifests", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				manifests /not/exist
+				serve_stale
+			}`
+		})
+		It("should return serve_stale error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("serve_stale"))
+		})
+	})
+
+	When("client_cidr has invalid CIDR", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				client_cidr 10.20.0.0 chaoyang beijing north
+			}`
+		})
+		It("should return client_cidr error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("client_cidr"))
+		})
+	})
+
+	When("unknown order is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				order weighted
+			}`
+		})
+		It("should return order error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("unknown order"))
+		})
+	})
+
+	When("negative max_answers is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				max_answers -1
+			}`
+		})
+		It("should return max_answers error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("max_answers"))
+		})
+	})
+
 	When("unexpected ttl is specified", func() {
 		BeforeEach(func() {
 			config = `fabdns {
@@ -189,6 +564,17 @@ func testIncorrectConfig() {
 			Expect(parseErr.Error()).To(ContainSubstring("out of range"))
 		})
 	})
+
+	When("unexpected service_ttl is specified", func() {
+		BeforeEach(func() {
+			config = `fabdns {
+				service_ttl 600 10
+			}`
+		})
+		It("should return service_ttl error", func() {
+			Expect(parseErr.Error()).To(ContainSubstring("service_ttl"))
+		})
+	})
 }
 
 func testPluginRegistration() {
@@ -0,0 +1,75 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"errors"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const defaultMaxStaleness = time.Hour
+
+var errStaleExpired = errors.New("global services are staler than max staleness")
+
+// ServeStale keeps answering queries with cached global services when API server is
+// unreachable, until the cache is staler than MaxStaleness.
+type ServeStale struct {
+	// MaxStaleness is how long cached global services are used after API server is unreachable
+	MaxStaleness time.Duration
+	// TTL is the TTL of stale answers, TTL of fabdns is used if it's nil
+	TTL *uint32
+}
+
+// serveStale returns the TTL of stale answers if cached global services are stale and
+// serve_stale is configured, nil is returned if they are not. errStaleExpired is returned
+// if API server is unreachable longer than maxUnreachable, so cached global services are
+// not used forever even if serve_stale is not configured.
+func (f FabDNS) serveStale() (*uint32, error) {
+	if f.monitor == nil {
+		return nil, nil
+	}
+
+	staleness, stale := f.monitor.unreachableFor()
+	if !stale {
+		return nil, nil
+	}
+
+	if staleness > f.maxUnreachable() {
+		return nil, errStaleExpired
+	}
+
+	if f.ServeStale == nil {
+		return nil, nil
+	}
+
+	ttl := f.TTL
+	if f.ServeStale.TTL != nil {
+		ttl = *f.ServeStale.TTL
+	}
+	return &ttl, nil
+}
+
+// capTTL caps TTL of records in m by ttl
+func capTTL(m *dns.Msg, ttl uint32) {
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT && rr.Header().Ttl > ttl {
+				rr.Header().Ttl = ttl
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("ServeStale", func() {
+	var (
+		svcStale     = "stale-nginx"
+		qname        = fmt.Sprintf("%s.%s.svc.%s", svcStale, namespaceDefault, testZone)
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+		probeErr     error
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcStale,
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.4.1"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		probeErr = nil
+		staleTTL := uint32(5)
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    30,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+			ServeStale: &ServeStale{
+				MaxStaleness: time.Hour,
+				TTL:          &staleTTL,
+			},
+			monitor: &apiServerMonitor{
+				probe: func(ctx context.Context) error {
+					return probeErr
+				},
+				lastContact: time.Now(),
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	serve := func() *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		req.SetEdns0(4096, false)
+
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := fabdns.ServeDNS(context.TODO(), testRecorder, req)
+		Expect(err).To(Succeed())
+		return testRecorder.Msg
+	}
+
+	It("should answer normally if API server is reachable", func() {
+		fabdns.monitor.probeOnce(context.TODO())
+
+		resp := serve()
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+		Expect(resp.IsEdns0()).NotTo(BeNil())
+		Expect(resp.IsEdns0().Option).To(BeEmpty())
+	})
+
+	It("should answer with stale TTL and extended error if API server is unreachable", func() {
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+
+		resp := serve()
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].Header().Ttl).To(Equal(uint32(5)))
+		Expect(resp.IsEdns0()).NotTo(BeNil())
+		Expect(resp.IsEdns0().Option).To(ContainElement(&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeStaleAnswer}))
+	})
+
+	It("should stop serving stale answers after API server is reachable again", func() {
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+		probeErr = nil
+		fabdns.monitor.probeOnce(context.TODO())
+
+		_, stale := fabdns.monitor.unreachableFor()
+		Expect(stale).To(BeFalse())
+	})
+
+	It("should return SERVFAIL if global services are staler than max staleness", func() {
+		fabdns.monitor.lastContact = time.Now().Add(-2 * time.Hour)
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+
+		req := new(dns.Msg)
+		req.SetQuestion(qname, dns.TypeA)
+		rcode, err := fabdns.ServeDNS(context.TODO(), testRecorder, req)
+		Expect(rcode).To(Equal(dns.RcodeServerFailure))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should answer with stale TTL 0 if it's configured", func() {
+		staleTTL := uint32(0)
+		fabdns.ServeStale.TTL = &staleTTL
+		probeErr = errors.New("connection refused")
+		fabdns.monitor.probeOnce(context.TODO())
+
+		resp := serve()
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].Header().Ttl).To(BeZero())
+	})
+
+	When("serve_stale is not configured", func() {
+		BeforeEach(func() {
+			fabdns.ServeStale = nil
+		})
+
+		It("should answer normally if API server is unreachable shortly", func() {
+			probeErr = errors.New("connection refused")
+			fabdns.monitor.probeOnce(context.TODO())
+
+			resp := serve()
+			Expect(resp.Answer).To(HaveLen(1))
+			Expect(resp.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+			Expect(resp.IsEdns0().Option).To(BeEmpty())
+		})
+
+		It("should return SERVFAIL if API server is unreachable longer than grace period", func() {
+			fabdns.monitor.lastContact = time.Now().Add(-time.Minute)
+			probeErr = errors.New("connection refused")
+			fabdns.monitor.probeOnce(context.TODO())
+
+			req := new(dns.Msg)
+			req.SetQuestion(qname, dns.TypeA)
+			rcode, err := fabdns.ServeDNS(context.TODO(), testRecorder, req)
+			Expect(rcode).To(Equal(dns.RcodeServerFailure))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,368 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// notifyInterval is how often fabdns checks if zones are changed and sends NOTIFY
+const notifyInterval = 5 * time.Second
+
+var errTransferWrite = errors.New("responses of zone transfer are not written to response writer")
+
+var _ transfer.Transferer = FabDNS{}
+
+// zoneSerial is the serial of SOA records. Changes of global services only mark it dirty,
+// the next read increases it to the current unix time, or by 1 if it's not less than that,
+// so changes between two reads bump the serial once.
+type zoneSerial struct {
+	mu    sync.Mutex
+	value uint32
+	dirty bool
+}
+
+func newZoneSerial() *zoneSerial {
+	return &zoneSerial{value: uint32(time.Now().Unix())}
+}
+
+func (s *zoneSerial) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty = true
+}
+
+func (s *zoneSerial) get() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dirty {
+		now := uint32(time.Now().Unix())
+		if now > s.value {
+			s.value = now
+		} else {
+			s.value++
+		}
+		s.dirty = false
+	}
+
+	return s.value
+}
+
+// watch marks serial dirty when objects of informers in informerCache are changed
+func (s *zoneSerial) watch(informerCache cache.Cache, objects ...client.Object) error {
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.touch()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// periodic resyncs don't change objects
+			oldMeta, ok1 := oldObj.(client.Object)
+			newMeta, ok2 := newObj.(client.Object)
+			if ok1 && ok2 && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			s.touch()
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.touch()
+		},
+	}
+
+	for _, obj := range objects {
+		informer, err := informerCache.GetInformer(context.Background(), obj)
+		if err != nil {
+			return err
+		}
+		informer.AddEventHandler(handler)
+	}
+
+	return nil
+}
+
+// notifyChanges sends NOTIFY of zones by transfer plugin when serial is changed, until ctx is done
+func (f FabDNS) notifyChanges(ctx context.Context) {
+	notified := f.zoneSerial.get()
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		serial := f.zoneSerial.get()
+		if serial == notified {
+			return
+		}
+		notified = serial
+
+		for _, zone := range f.Zones {
+			if dns.IsSubDomain("arpa.", zone) {
+				continue
+			}
+			if err := f.transfer.Notify(zone); err != nil {
+				log.Warningf("failed to notify changes of zone %s: %s", zone, err)
+			}
+		}
+	}, notifyInterval)
+}
+
+// Transfer implements transfer.Transferer interface. All names of global services which
+// fabdns answers are transferred, including normal, ad-hoc, headless hostname names and
+// SRV names of them, records are generated as if the query comes from the cluster of fabdns.
+// Changes are not journaled, so IXFR falls back to AXFR if the serial is out of date.
+// Reverse zones and AAAA records synthesized by DNS64 are not transferred.
+func (f FabDNS) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if !f.isTransferZone(zone) {
+		return nil, transfer.ErrNotAuthoritative
+	}
+
+	if !f.isCacheSynced() {
+		return nil, errCacheNotSynced
+	}
+
+	// records of the same serial must be the same, so weighted picking and health check,
+	// which change answers without changing serial, are not used
+	f.DNS64Prefix = nil
+	f.HealthCheck = nil
+	f.ignoreWeights = true
+
+	soa := f.soa(zone)
+	ch := make(chan []dns.RR)
+
+	if serial != 0 && serial >= soa.Serial {
+		go func() {
+			ch <- []dns.RR{soa}
+			close(ch)
+		}()
+		return ch, nil
+	}
+
+	var globalServices apis.GlobalServiceList
+	if err := f.listObjects(&globalServices); err != nil {
+		return nil, err
+	}
+
+	var recordsOfServices [][]dns.RR
+	for _, globalService := range globalServices.Items {
+		records, err := f.transferRecords(zone, globalService)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			recordsOfServices = append(recordsOfServices, records)
+		}
+	}
+
+	// the nameserver is in zone, its address records are needed by secondaries to resolve it
+	var glue []dns.RR
+	for _, ip := range f.nameserverIPs {
+		state := request.Request{W: transferWriter{localIP: ip}}
+		glue = append(glue, f.nameserverAddressRecords(&state, nameserverName(zone), 0)...)
+	}
+
+	go func() {
+		ch <- []dns.RR{soa}
+		ch <- []dns.RR{&dns.NS{
+			Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: f.TTL},
+			Ns:  nameserverName(zone),
+		}}
+		if len(glue) > 0 {
+			ch <- glue
+		}
+		for _, records := range recordsOfServices {
+			ch <- records
+		}
+		ch <- []dns.RR{soa}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// isTransferZone returns true if zone is one of zones of fabdns and not a reverse zone
+func (f FabDNS) isTransferZone(zone string) bool {
+	if dns.IsSubDomain("arpa.", zone) {
+		return false
+	}
+
+	for _, z := range f.Zones {
+		if strings.EqualFold(z, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// transferRecords returns records of all names of globalService in zone
+func (f FabDNS) transferRecords(zone string, globalService apis.GlobalService) ([]dns.RR, error) {
+	var records []dns.RR
+	for _, name := range f.transferNames(zone, globalService) {
+		parsedReq, err := f.parseRequest(name, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		qtypes := []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeSRV}
+		if parsedReq.isSRV() {
+			qtypes = []uint16{dns.TypeSRV}
+		} else if parsedReq.hostname != "" {
+			qtypes = []uint16{dns.TypeA, dns.TypeAAAA}
+		}
+
+		for _, qtype := range qtypes {
+			req := new(dns.Msg)
+			req.SetQuestion(name, qtype)
+			state := request.Request{W: transferWriter{}, Req: req, Zone: zone}
+
+			nameRecords, err := f.getRecords(&state, parsedReq, &queryInfo{})
+			if err != nil {
+				if f.IsNameError(err) {
+					continue
+				}
+				return nil, err
+			}
+			records = append(records, nameRecords...)
+		}
+	}
+
+	return dns.Dedup(records, nil), nil
+}
+
+// transferNames returns names of globalService by the naming scheme of fabdns
+func (f FabDNS) transferNames(zone string, globalService apis.GlobalService) []string {
+	serviceName := fmt.Sprintf("%s.%s.%s.%s", globalService.Name, globalService.Namespace, LabelSVC, zone)
+	names := withSRVNames([]string{serviceName}, serviceName, globalService.Spec.Ports)
+
+	var clusters, hostnames []string
+	for _, endpoint := range globalService.Spec.Endpoints {
+		if globalService.Spec.Type == apis.Headless && endpoint.Hostname != nil {
+			names = append(names, fmt.Sprintf("%s.%s.%s", *endpoint.Hostname, endpoint.Cluster, serviceName))
+			if !containsString(hostnames, *endpoint.Hostname) {
+				hostnames = append(hostnames, *endpoint.Hostname)
+			}
+		}
+		if !containsString(clusters, endpoint.Cluster) {
+			clusters = append(clusters, endpoint.Cluster)
+		}
+	}
+
+	// ad-hoc names don't exist in mcs naming scheme
+	if !f.isMCSNaming() {
+		for _, cluster := range clusters {
+			clusterName := fmt.Sprintf("%s.%s", cluster, serviceName)
+			names = withSRVNames(append(names, clusterName), clusterName, globalService.Spec.Ports)
+		}
+
+		// cross-cluster hostnames have the same format as ad-hoc names, which win on conflicts
+		if f.HostnameCollision != "" {
+			for _, hostname := range hostnames {
+				if !containsString(clusters, hostname) {
+					names = append(names, fmt.Sprintf("%s.%s", hostname, serviceName))
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// withSRVNames appends SRV names of ports with name, e.g. _http._tcp.{name}, to names
+func withSRVNames(names []string, name string, ports []apis.ServicePort) []string {
+	for _, port := range ports {
+		if port.Name == "" {
+			continue
+		}
+		protocol := string(port.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		names = append(names, fmt.Sprintf("_%s._%s.%s", strings.ToLower(port.Name), strings.ToLower(protocol), name))
+	}
+	return names
+}
+
+// transferWriter is the response writer of requests built in zone transfer, records are sent
+// through the transfer channel, so nothing is written to it. localIP is the address of the
+// nameserver which the records are generated for.
+type transferWriter struct {
+	localIP net.IP
+}
+
+func (w transferWriter) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: w.localIP}
+}
+
+func (w transferWriter) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (w transferWriter) WriteMsg(*dns.Msg) error {
+	return errTransferWrite
+}
+
+func (w transferWriter) Write([]byte) (int, error) {
+	return 0, errTransferWrite
+}
+
+func (w transferWriter) Close() error {
+	return nil
+}
+
+func (w transferWriter) TsigStatus() error {
+	return nil
+}
+
+func (w transferWriter) TsigTimersOnly(bool) {}
+
+func (w transferWriter) Hijack() {}
+
+// listenIPs returns IPs of hosts which the server listens on, addresses of interfaces except
+// loopback and link-local ones are returned if the server listens on wildcard addresses
+func listenIPs(hosts []string) []net.IP {
+	var ips []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) > 0 {
+		return ips
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Warningf("failed to get addresses of interfaces: %s", err)
+		return nil
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips
+}
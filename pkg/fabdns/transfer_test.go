@@ -0,0 +1,210 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Transfer", func() {
+	var fabdns *FabDNS
+
+	BeforeEach(func() {
+		hostname := "mysql-0"
+		nginx := apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type:  apis.ClusterIP,
+				Ports: []apis.ServicePort{{Name: "web", Port: 80, Protocol: corev1.ProtocolTCP}},
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+					{Addresses: []string{"fd00::1"}, Cluster: "guangzhou", Zone: "guangzhou", Region: "south"},
+				},
+			},
+		}
+		mysql := apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.Headless,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.2"}, Cluster: "shanghai", Zone: "shanghai", Region: "south", Hostname: &hostname},
+				},
+			},
+		}
+
+		fabdns = &FabDNS{
+			Zones: []string{testZone, "in-addr.arpa."},
+			TTL:   5,
+			Client: &fileStore{
+				globalServices: map[client.ObjectKey]apis.GlobalService{
+					client.ObjectKeyFromObject(&nginx): nginx,
+					client.ObjectKeyFromObject(&mysql): mysql,
+				},
+			},
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+			Policy:     Policy{tierAll},
+			zoneSerial: newZoneSerial(),
+		}
+	})
+
+	receive := func(ch <-chan []dns.RR) []string {
+		var records []string
+		for rrs := range ch {
+			ExpectWithOffset(1, rrs).NotTo(BeEmpty())
+			for _, rr := range rrs {
+				records = append(records, rr.String())
+			}
+		}
+		return records
+	}
+
+	It("should transfer all names of global services", func() {
+		ch, err := fabdns.Transfer(testZone, 0)
+		Expect(err).To(Succeed())
+
+		records := receive(ch)
+		soa := fabdns.soa(testZone).String()
+		Expect(records[0]).To(Equal(soa))
+		Expect(records[len(records)-1]).To(Equal(soa))
+		Expect(records).To(ContainElements(
+			"testzone.\t5\tIN\tNS\tns.dns.testzone.",
+			"nginx.lab.svc.testzone.\t5\tIN\tA\t192.168.12.1",
+			"nginx.lab.svc.testzone.\t5\tIN\tAAAA\tfd00::1",
+			"nginx.lab.svc.testzone.\t5\tIN\tSRV\t0 100 80 nginx.lab.svc.testzone.",
+			"_web._tcp.nginx.lab.svc.testzone.\t5\tIN\tSRV\t0 100 80 nginx.lab.svc.testzone.",
+			"shanghai.nginx.lab.svc.testzone.\t5\tIN\tA\t192.168.12.1",
+			"_web._tcp.guangzhou.nginx.lab.svc.testzone.\t5\tIN\tSRV\t0 100 80 guangzhou.nginx.lab.svc.testzone.",
+			"mysql.lab.svc.testzone.\t5\tIN\tA\t192.168.12.2",
+			"mysql-0.shanghai.mysql.lab.svc.testzone.\t5\tIN\tA\t192.168.12.2",
+		))
+		Expect(records).To(HaveLen(16))
+	})
+
+	It("should transfer address records of the nameserver", func() {
+		fabdns.nameserverIPs = []net.IP{net.ParseIP("10.10.0.2"), net.ParseIP("fd00::53")}
+
+		ch, err := fabdns.Transfer(testZone, 0)
+		Expect(err).To(Succeed())
+
+		records := receive(ch)
+		Expect(records).To(ContainElements(
+			"ns.dns.testzone.\t5\tIN\tA\t10.10.0.2",
+			"ns.dns.testzone.\t5\tIN\tAAAA\tfd00::53",
+		))
+		Expect(records).To(HaveLen(18))
+	})
+
+	It("should get IPs which the server listens on", func() {
+		Expect(listenIPs([]string{"10.10.0.2", "::"})).To(Equal([]net.IP{net.ParseIP("10.10.0.2")}))
+		for _, ip := range listenIPs([]string{""}) {
+			Expect(ip.IsLoopback()).To(BeFalse())
+		}
+	})
+
+	It("should not transfer ad-hoc names in mcs naming scheme", func() {
+		fabdns.Naming = NamingMCS
+
+		ch, err := fabdns.Transfer(testZone, 0)
+		Expect(err).To(Succeed())
+		Expect(receive(ch)).NotTo(ContainElement(HavePrefix("shanghai.nginx")))
+	})
+
+	It("should transfer hostnames without cluster if cross cluster hostname is enabled", func() {
+		fabdns.HostnameCollision = HostnameCollisionNXDomain
+
+		ch, err := fabdns.Transfer(testZone, 0)
+		Expect(err).To(Succeed())
+		Expect(receive(ch)).To(ContainElement("mysql-0.mysql.lab.svc.testzone.\t5\tIN\tA\t192.168.12.2"))
+	})
+
+	It("should transfer the same records regardless of weights and health check", func() {
+		store := fabdns.Client.(*fileStore)
+		key := client.ObjectKey{Name: "nginx", Namespace: "lab"}
+		nginx := store.globalServices[key]
+		nginx.Spec.Endpoints = append(nginx.Spec.Endpoints, apis.Endpoint{
+			Addresses: []string{"192.168.12.3"}, Cluster: "guangzhou", Zone: "guangzhou", Region: "south",
+		})
+		weight := int32(1)
+		for i := range nginx.Spec.Endpoints {
+			nginx.Spec.Endpoints[i].Weight = &weight
+		}
+		store.globalServices[key] = nginx
+
+		fabdns.HealthCheck = newHealthCheck()
+		fabdns.HealthCheck.targets["192.168.12.1"] = &probeTarget{cluster: "shanghai", failures: fabdns.HealthCheck.Failures}
+
+		for i := 0; i < 5; i++ {
+			ch, err := fabdns.Transfer(testZone, 0)
+			Expect(err).To(Succeed())
+			Expect(receive(ch)).To(ContainElements(
+				"nginx.lab.svc.testzone.\t5\tIN\tA\t192.168.12.1",
+				"nginx.lab.svc.testzone.\t5\tIN\tA\t192.168.12.3",
+			))
+		}
+	})
+
+	It("should only send SOA if serial of IXFR is up to date", func() {
+		serial := fabdns.serial()
+
+		ch, err := fabdns.Transfer(testZone, serial)
+		Expect(err).To(Succeed())
+		Expect(receive(ch)).To(Equal([]string{fabdns.soa(testZone).String()}))
+	})
+
+	It("should fall back to AXFR if serial of IXFR is out of date", func() {
+		serial := fabdns.serial()
+		fabdns.zoneSerial.touch()
+
+		ch, err := fabdns.Transfer(testZone, serial)
+		Expect(err).To(Succeed())
+		Expect(len(receive(ch))).To(BeNumerically(">", 2))
+	})
+
+	It("should not be authoritative for other zones and reverse zones", func() {
+		_, err := fabdns.Transfer("example.org.", 0)
+		Expect(err).To(Equal(transfer.ErrNotAuthoritative))
+
+		_, err = fabdns.Transfer("in-addr.arpa.", 0)
+		Expect(err).To(Equal(transfer.ErrNotAuthoritative))
+	})
+
+	It("should bump serial once for changes between two reads", func() {
+		serial := fabdns.zoneSerial.get()
+		Expect(fabdns.zoneSerial.get()).To(Equal(serial))
+
+		fabdns.zoneSerial.touch()
+		fabdns.zoneSerial.touch()
+		bumped := fabdns.zoneSerial.get()
+		Expect(bumped).To(BeNumerically(">", serial))
+		Expect(fabdns.zoneSerial.get()).To(Equal(bumped))
+
+		fabdns.zoneSerial.touch()
+		Expect(fabdns.zoneSerial.get()).To(BeNumerically(">", bumped))
+	})
+})
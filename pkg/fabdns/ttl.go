@@ -0,0 +1,75 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"strconv"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
+)
+
+const maxTTL = 3600
+
+// parseServiceTTL parses service_ttl arguments in the format of MIN MAX
+func parseServiceTTL(args []string) (uint32, uint32, error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("expected MIN MAX")
+	}
+
+	var bounds [2]uint32
+	for i, arg := range args {
+		ttl, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ttl > maxTTL {
+			return 0, 0, fmt.Errorf("ttl %d is out of range [0, %d]", ttl, maxTTL)
+		}
+		bounds[i] = uint32(ttl)
+	}
+
+	if bounds[0] > bounds[1] {
+		return 0, 0, fmt.Errorf("min %d is greater than max %d", bounds[0], bounds[1])
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// ttlOf returns TTL of records of globalService, which is specified by its annotation and
+// bounded by MinServiceTTL and MaxServiceTTL. TTL of fabdns is used if the annotation is
+// absent or invalid.
+func (f FabDNS) ttlOf(globalService apis.GlobalService) uint32 {
+	value, found := globalService.Annotations[constants.KeyGlobalServiceTTL]
+	if !found {
+		return f.TTL
+	}
+
+	ttl, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		log.Debugf("invalid TTL %q of global service %s/%s", value, globalService.Namespace, globalService.Name)
+		return f.TTL
+	}
+
+	switch {
+	case ttl < uint64(f.MinServiceTTL):
+		return f.MinServiceTTL
+	case ttl > uint64(f.MaxServiceTTL):
+		return f.MaxServiceTTL
+	default:
+		return uint32(ttl)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
+)
+
+var _ = Describe("TTL", func() {
+	var fabdns *FabDNS
+
+	globalServiceWithTTL := func(ttl string) apis.GlobalService {
+		globalService := apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion},
+				},
+			},
+		}
+		if ttl != "" {
+			globalService.Annotations = map[string]string{constants.KeyGlobalServiceTTL: ttl}
+		}
+		return globalService
+	}
+
+	BeforeEach(func() {
+		fabdns = &FabDNS{
+			Zones:         []string{testZone},
+			TTL:           5,
+			MinServiceTTL: 0,
+			MaxServiceTTL: 600,
+		}
+	})
+
+	It("should use TTL of fabdns if global service has no valid TTL annotation", func() {
+		Expect(fabdns.ttlOf(globalServiceWithTTL(""))).To(Equal(uint32(5)))
+		Expect(fabdns.ttlOf(globalServiceWithTTL("ten"))).To(Equal(uint32(5)))
+		Expect(fabdns.ttlOf(globalServiceWithTTL("-1"))).To(Equal(uint32(5)))
+	})
+
+	It("should bound TTL annotation by min and max service TTL", func() {
+		fabdns.MinServiceTTL = 10
+
+		Expect(fabdns.ttlOf(globalServiceWithTTL("300"))).To(Equal(uint32(300)))
+		Expect(fabdns.ttlOf(globalServiceWithTTL("0"))).To(Equal(uint32(10)))
+		Expect(fabdns.ttlOf(globalServiceWithTTL("86400"))).To(Equal(uint32(600)))
+	})
+
+	It("should answer queries with TTL of global service", func() {
+		globalService := globalServiceWithTTL("300")
+		fabdns.Client = &fileStore{
+			globalServices: map[client.ObjectKey]apis.GlobalService{
+				client.ObjectKeyFromObject(&globalService): globalService,
+			},
+		}
+
+		for _, qname := range []string{
+			fmt.Sprintf("mysql.lab.svc.%s", testZone),
+			fmt.Sprintf("%s.mysql.lab.svc.%s", testLocalCluster, testZone),
+		} {
+			executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+				Qname: qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Answer: []dns.RR{
+					test.A(fmt.Sprintf("%s    300    IN    A    192.168.12.1", qname)),
+				},
+			})
+		}
+	})
+
+	It("should parse service_ttl arguments", func() {
+		min, max, err := parseServiceTTL([]string{"30", "600"})
+		Expect(err).To(Succeed())
+		Expect(min).To(Equal(uint32(30)))
+		Expect(max).To(Equal(uint32(600)))
+
+		for _, args := range [][]string{
+			{"30"},
+			{"600", "30"},
+			{"30", "7200"},
+			{"-1", "30"},
+		} {
+			_, _, err = parseServiceTTL(args)
+			Expect(err).To(HaveOccurred(), fmt.Sprintf("%v", args))
+		}
+	})
+})
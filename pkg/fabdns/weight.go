@@ -0,0 +1,75 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"math/rand"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+// isEndpointDrained returns true if the weight of endpoint is 0, such endpoints are not
+// selected by tiers of policy, but they are still answered in explicit queries
+func isEndpointDrained(endpoint apis.Endpoint) bool {
+	return endpoint.Weight != nil && *endpoint.Weight == 0
+}
+
+// pickClusterByWeight picks a cluster of endpoints randomly in proportion to the weights
+// of clusters, the weight of a cluster is the max weight of its endpoints. False is returned
+// if none of endpoints has weight or all clusters have weight 0, all clusters should be used then.
+func pickClusterByWeight(endpoints []apis.Endpoint) (string, bool) {
+	var (
+		weighted bool
+		clusters []string
+		weights  = make(map[string]int32)
+	)
+	for _, endpoint := range endpoints {
+		weight := int32(apis.DefaultEndpointWeight)
+		if endpoint.Weight != nil {
+			weighted = true
+			weight = *endpoint.Weight
+		}
+
+		current, found := weights[endpoint.Cluster]
+		if !found {
+			clusters = append(clusters, endpoint.Cluster)
+		}
+		if !found || weight > current {
+			weights[endpoint.Cluster] = weight
+		}
+	}
+
+	if !weighted || len(clusters) < 2 {
+		return "", false
+	}
+
+	var total int32
+	for _, cluster := range clusters {
+		total += weights[cluster]
+	}
+	if total <= 0 {
+		return "", false
+	}
+
+	n := rand.Int31n(total)
+	for _, cluster := range clusters {
+		if n < weights[cluster] {
+			return cluster, true
+		}
+		n -= weights[cluster]
+	}
+
+	return "", false
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Weight", func() {
+	weightOf := func(weight int32) *int32 {
+		return &weight
+	}
+
+	It("should not pick cluster if no endpoint has weight", func() {
+		_, ok := pickClusterByWeight([]apis.Endpoint{
+			{Cluster: "chaoyang"},
+			{Cluster: "minhang"},
+		})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not pick cluster if all clusters have weight 0", func() {
+		_, ok := pickClusterByWeight([]apis.Endpoint{
+			{Cluster: "chaoyang", Weight: weightOf(0)},
+			{Cluster: "minhang", Weight: weightOf(0)},
+		})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should never pick cluster with weight 0", func() {
+		for i := 0; i < 100; i++ {
+			cluster, ok := pickClusterByWeight([]apis.Endpoint{
+				{Cluster: "chaoyang", Weight: weightOf(0)},
+				{Cluster: "minhang"},
+			})
+			Expect(ok).To(BeTrue())
+			Expect(cluster).To(Equal("minhang"))
+		}
+	})
+
+	It("should pick clusters in proportion to their weights", func() {
+		endpoints := []apis.Endpoint{
+			{Cluster: "chaoyang", Weight: weightOf(90)},
+			{Cluster: "chaoyang", Weight: weightOf(90)},
+			{Cluster: "minhang", Weight: weightOf(10)},
+		}
+
+		counts := make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			cluster, ok := pickClusterByWeight(endpoints)
+			Expect(ok).To(BeTrue())
+			counts[cluster]++
+		}
+
+		Expect(counts["chaoyang"]).To(BeNumerically("~", 9000, 300))
+		Expect(counts["minhang"]).To(BeNumerically("~", 1000, 300))
+	})
+
+	It("should skip tiers which only have endpoints of weight 0", func() {
+		qname := fmt.Sprintf("nginx.lab.svc.%s", testZone)
+		globalService := apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "lab"},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{Addresses: []string{"192.168.12.1"}, Cluster: testLocalCluster, Zone: testClusterZone, Region: testClusterRegion, Weight: weightOf(0)},
+					{Addresses: []string{"192.168.12.2"}, Cluster: "shanghai", Zone: "shanghai", Region: "south"},
+				},
+			},
+		}
+		fabdns := &FabDNS{
+			Zones: []string{testZone},
+			TTL:   5,
+			Client: &fileStore{
+				globalServices: map[client.ObjectKey]apis.GlobalService{
+					client.ObjectKeyFromObject(&globalService): globalService,
+				},
+			},
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname:  qname,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: []dns.RR{test.A(fmt.Sprintf("%s    5    IN    A    192.168.12.2", qname))},
+		})
+
+		adHocName := fmt.Sprintf("%s.%s", testLocalCluster, qname)
+		executeTestCase(fabdns, dnstest.NewRecorder(&test.ResponseWriter{}), test.Case{
+			Qname:  adHocName,
+			Qtype:  dns.TypeA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: []dns.RR{test.A(fmt.Sprintf("%s    5    IN    A    192.168.12.1", adHocName))},
+		})
+	})
+})
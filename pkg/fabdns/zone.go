@@ -0,0 +1,181 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+const (
+	soaRefresh = 7200
+	soaRetry   = 1800
+	soaExpire  = 86400
+)
+
+// soa returns the synthesized SOA record of zone, the minimum TTL of SOA is the same as
+// TTL of fabdns so that negative answers are cached as long as positive ones.
+func (f FabDNS) soa(zone string) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: f.TTL},
+		Ns:      nameserverName(zone),
+		Mbox:    dnsutil.Join("hostmaster", zone),
+		Serial:  f.serial(),
+		Refresh: soaRefresh,
+		Retry:   soaRetry,
+		Expire:  soaExpire,
+		Minttl:  f.TTL,
+	}
+}
+
+// serial returns the serial of SOA records, it's the current time if changes of global
+// services are not tracked
+func (f FabDNS) serial() uint32 {
+	if f.zoneSerial != nil {
+		return f.zoneSerial.get()
+	}
+	return uint32(time.Now().Unix())
+}
+
+// nameserverName returns the name of nameserver of zone, which is ns.dns.{zone}
+func nameserverName(zone string) string {
+	return dnsutil.Join("ns.dns", zone)
+}
+
+// isZoneName returns true if qname is the apex or the nameserver of zone, these names
+// are answered by fabdns itself instead of global services.
+func isZoneName(qname, zone string) bool {
+	return strings.EqualFold(qname, zone) || strings.EqualFold(qname, nameserverName(zone))
+}
+
+// getZoneRecords returns records of zone apex and nameserver, an empty answer is returned
+// if query type is not supported by the name, which means NODATA.
+func (f FabDNS) getZoneRecords(state *request.Request) (records, extras []dns.RR) {
+	if strings.EqualFold(state.QName(), state.Zone) {
+		switch state.QType() {
+		case dns.TypeSOA:
+			records = append(records, f.soa(state.Zone))
+		case dns.TypeNS:
+			records = append(records, &dns.NS{
+				Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: f.TTL},
+				Ns:  nameserverName(state.Zone),
+			})
+			extras = f.nameserverAddressRecords(state, nameserverName(state.Zone), 0)
+		}
+		return records, extras
+	}
+
+	return f.nameserverAddressRecords(state, state.QName(), state.QType()), nil
+}
+
+// nameserverAddressRecords returns address records of nameserver, which is the local
+// address of the query. Both A and AAAA records are acceptable if qtype is 0.
+func (f FabDNS) nameserverAddressRecords(state *request.Request, name string, qtype uint16) []dns.RR {
+	ip := net.ParseIP(state.LocalIP())
+	if ip == nil {
+		return nil
+	}
+
+	hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: f.TTL}
+	if isIPv4(ip) {
+		if qtype != 0 && qtype != dns.TypeA {
+			return nil
+		}
+		hdr.Rrtype = dns.TypeA
+		return []dns.RR{&dns.A{Hdr: hdr, A: ip.To4()}}
+	}
+
+	if qtype != 0 && qtype != dns.TypeAAAA {
+		return nil
+	}
+	hdr.Rrtype = dns.TypeAAAA
+	return []dns.RR{&dns.AAAA{Hdr: hdr, AAAA: ip.To16()}}
+}
+
+// isEmptyNonTerminal returns true if query name is svc.{zone} or {namespace}.svc.{zone}
+// and there are global services under it, or query name is _{protocol}.{name} and any
+// SRV name _{port}._{protocol}.{name} exists. Such names exist in zone without any records,
+// they need NODATA instead of NXDOMAIN, otherwise resolvers which minimize query names
+// would stop resolving global services. Namespaces which are not allowed are treated as
+// not existing, so they can't be discovered by the difference of answers.
+func (f FabDNS) isEmptyNonTerminal(state *request.Request) bool {
+	name, err := dnsutil.TrimZone(state.QName(), state.Zone)
+	if err != nil {
+		return false
+	}
+	labels := dns.SplitDomainName(name)
+
+	switch {
+	case len(labels) == 1 && labels[0] == LabelSVC:
+		return true
+	case len(labels) == 2 && labels[1] == LabelSVC:
+		if !f.isNamespaceAllowed(labels[0]) {
+			return false
+		}
+
+		var globalServices apis.GlobalServiceList
+		err := f.listObjects(&globalServices, client.InNamespace(labels[0]))
+		if err != nil {
+			log.Errorf("failed to list GlobalServices in namespace %s: %v", labels[0], err)
+			return false
+		}
+		return len(globalServices.Items) > 0
+	case len(labels) > 1 && strings.HasPrefix(labels[0], "_") && !strings.HasPrefix(labels[1], "_"):
+		return f.isProtocolNonTerminal(state, strings.TrimPrefix(labels[0], "_"))
+	default:
+		return false
+	}
+}
+
+// isProtocolNonTerminal returns true if any SRV name _{port}._{protocol}.{name} exists,
+// query name of state is _{protocol}.{name}
+func (f FabDNS) isProtocolNonTerminal(state *request.Request, protocol string) bool {
+	qname := state.QName()
+	parsedReq, err := f.parseRequest(qname[strings.Index(qname, ".")+1:], state.Zone)
+	if err != nil || parsedReq.hostname != "" {
+		return false
+	}
+
+	globalService, err := f.getGlobalService(state, parsedReq)
+	if err != nil {
+		return false
+	}
+
+	for _, port := range globalService.Spec.Ports {
+		if port.Name == "" {
+			continue
+		}
+
+		srvReq := parsedReq
+		srvReq.port, srvReq.protocol = strings.ToLower(port.Name), protocol
+		srvState := state.NewWithQuestion(fmt.Sprintf("_%s.%s", srvReq.port, qname), dns.TypeSRV)
+		srvState.Zone = state.Zone
+
+		if _, err := f.getRecords(&srvState, srvReq, &queryInfo{}); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabdns
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+)
+
+var _ = Describe("Zone", func() {
+	var (
+		soa          = test.SOA(fmt.Sprintf("%s    5    IN    SOA    ns.dns.%s hostmaster.%s 303 7200 1800 86400 5", testZone, testZone, testZone))
+		nameserver   = "ns.dns." + testZone
+		testService  apis.GlobalService
+		testRecorder *dnstest.Recorder
+		fabdns       *FabDNS
+	)
+
+	BeforeEach(func() {
+		testService = apis.GlobalService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "zone-nginx",
+				Namespace: namespaceDefault,
+			},
+			Spec: apis.GlobalServiceSpec{
+				Type: apis.ClusterIP,
+				Endpoints: []apis.Endpoint{
+					{
+						Cluster:   testLocalCluster,
+						Region:    testClusterRegion,
+						Zone:      testClusterZone,
+						Addresses: []string{"192.168.1.1"},
+					},
+				},
+			},
+		}
+		createGlobalService(testK8sClient, &testService)
+
+		fabdns = &FabDNS{
+			Zones:  []string{testZone},
+			TTL:    5,
+			Client: testK8sClient,
+			Cluster: ClusterInfo{
+				Name:   testLocalCluster,
+				Zone:   testClusterZone,
+				Region: testClusterRegion,
+			},
+		}
+		testRecorder = dnstest.NewRecorder(&test.ResponseWriter{})
+	})
+
+	AfterEach(func() {
+		deleteGlobalService(testK8sClient, &testService)
+	})
+
+	It("should answer SOA query of zone apex", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname:  testZone,
+			Qtype:  dns.TypeSOA,
+			Rcode:  dns.RcodeSuccess,
+			Answer: []dns.RR{soa},
+		})
+	})
+
+	It("should answer NS query of zone apex with address of nameserver", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: testZone,
+			Qtype: dns.TypeNS,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.NS(fmt.Sprintf("%s    5    IN    NS    %s", testZone, nameserver)),
+			},
+			Extra: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    127.0.0.1", nameserver)),
+			},
+		})
+	})
+
+	It("should answer A query of nameserver", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: nameserver,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A(fmt.Sprintf("%s    5    IN    A    127.0.0.1", nameserver)),
+			},
+		})
+	})
+
+	It("should answer NODATA with SOA for other query types of zone apex", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: testZone,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Ns:    []dns.RR{soa},
+		})
+	})
+
+	It("should answer NODATA with SOA for empty non-terminal names", func() {
+		for _, qname := range []string{"svc." + testZone, fmt.Sprintf("%s.svc.%s", namespaceDefault, testZone)} {
+			executeTestCase(fabdns, testRecorder, test.Case{
+				Qname: qname,
+				Qtype: dns.TypeA,
+				Rcode: dns.RcodeSuccess,
+				Ns:    []dns.RR{soa},
+			})
+		}
+	})
+
+	It("should answer NXDOMAIN with SOA for namespaces without global services", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: "unknown.svc." + testZone,
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+		Expect(testRecorder.Msg.Ns).To(HaveLen(1))
+		Expect(test.Section(test.Case{Ns: []dns.RR{soa}}, test.Ns, testRecorder.Msg.Ns)).To(Succeed())
+	})
+
+	It("should answer NXDOMAIN with SOA for namespaces which are not allowed", func() {
+		fabdns.ExcludeNamespaces = []NamespaceMatcher{{Pattern: namespaceDefault}}
+
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("%s.svc.%s", namespaceDefault, testZone),
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+		Expect(testRecorder.Msg.Ns).To(HaveLen(1))
+		Expect(test.Section(test.Case{Ns: []dns.RR{soa}}, test.Ns, testRecorder.Msg.Ns)).To(Succeed())
+	})
+
+	It("should answer NXDOMAIN with SOA for unknown global services", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("unknown.%s.svc.%s", namespaceDefault, testZone),
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+		Expect(testRecorder.Msg.Ns).To(HaveLen(1))
+		Expect(test.Section(test.Case{Ns: []dns.RR{soa}}, test.Ns, testRecorder.Msg.Ns)).To(Succeed())
+	})
+
+	It("should answer NXDOMAIN for clusters which have no endpoints", func() {
+		executeTestCase(fabdns, testRecorder, test.Case{
+			Qname: fmt.Sprintf("unknown.zone-nginx.%s.svc.%s", namespaceDefault, testZone),
+			Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+		})
+	})
+})
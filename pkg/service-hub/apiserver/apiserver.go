@@ -15,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	"github.com/fabedge/fab-dns/pkg/service-hub/types"
 )
 
@@ -73,12 +74,16 @@ func (s *Server) GetAllGlobalServices(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for i, svc := range globalServices.Items {
-		// clean useless fields
+		// clean useless fields, TTL annotation is kept for clusters which import the service
+		ttl, found := svc.Annotations[constants.KeyGlobalServiceTTL]
 		svc.ObjectMeta = metav1.ObjectMeta{
 			Name:            svc.Name,
 			Namespace:       svc.Namespace,
 			ResourceVersion: svc.ResourceVersion,
 		}
+		if found {
+			svc.Annotations = map[string]string{constants.KeyGlobalServiceTTL: ttl}
+		}
 		globalServices.Items[i] = svc
 	}
 
@@ -170,8 +175,10 @@ func (s *Server) updateClusterExpireTime(next http.Handler) http.Handler {
 			return
 		}
 
+		now := time.Now()
 		cluster := s.ClusterStore.New(clusterName)
-		cluster.SetExpireTime(time.Now().Add(s.ClusterExpireDuration))
+		cluster.SetExpireTime(now.Add(s.ClusterExpireDuration))
+		cluster.SetHeartbeatTime(now)
 
 		next.ServeHTTP(w, r)
 	}
@@ -19,6 +19,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	"github.com/fabedge/fab-dns/pkg/service-hub/apiserver"
 	"github.com/fabedge/fab-dns/pkg/service-hub/types"
 )
@@ -140,6 +141,21 @@ var _ = Describe("APIServer", func() {
 				}
 			}
 		})
+
+		It("will keep ttl annotation of global services", func() {
+			serviceFromBeijing.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "300"}
+			td.uploadGlobalService(serviceFromBeijing)
+
+			services := td.downloadAllGlobalServices("shanghai")
+			for _, svc := range services {
+				if svc.Namespace == namespaceDefault {
+					Expect(svc.Annotations).To(Equal(map[string]string{constants.KeyGlobalServiceTTL: "300"}))
+				}
+				if svc.Namespace == namespaceTest {
+					Expect(svc.Annotations).To(BeEmpty())
+				}
+			}
+		})
 	})
 })
 
@@ -17,9 +17,15 @@ type Config struct {
 	Interval            time.Duration
 	RequestTimeout      time.Duration
 	RevokeGlobalService types.RevokeGlobalServiceFunc
+
+	// UnavailableDuration is how long a cluster is marked unavailable after it stops heartbeat,
+	// 0 means clusters are never marked unavailable
+	UnavailableDuration    time.Duration
+	SetClusterAvailability types.SetClusterAvailabilityFunc
 }
 
-// clusterCleaner will run periodically and clean endpoints of global services of expired cluster
+// clusterCleaner will run periodically and clean endpoints of global services of expired cluster,
+// it also marks clusters which stop heartbeat as unavailable before their endpoints are cleaned
 type clusterCleaner struct {
 	Config
 	log    logr.Logger
@@ -41,6 +47,7 @@ func (cleaner *clusterCleaner) Start(ctx context.Context) error {
 	for {
 		select {
 		case <-tick.C:
+			cleaner.updateClusterAvailability()
 			cleaner.cleanExpiredClusterEndpoints()
 		case <-ctx.Done():
 			return nil
@@ -61,6 +68,29 @@ func (cleaner *clusterCleaner) cleanExpiredClusterEndpoints() {
 	}
 }
 
+func (cleaner *clusterCleaner) updateClusterAvailability() {
+	if cleaner.UnavailableDuration <= 0 || cleaner.SetClusterAvailability == nil {
+		return
+	}
+
+	for _, cluster := range cleaner.Store.GetAllClusters() {
+		available := !cluster.IsUnavailable(cleaner.UnavailableDuration)
+		for _, key := range cluster.GetAllServiceKeys() {
+			cleaner.setClusterAvailability(cluster.Name(), key, available)
+		}
+	}
+}
+
+func (cleaner *clusterCleaner) setClusterAvailability(clusterName string, key client.ObjectKey, available bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleaner.RequestTimeout)
+	defer cancel()
+
+	err := cleaner.SetClusterAvailability(ctx, clusterName, key.Namespace, key.Name, available)
+	if err != nil {
+		cleaner.log.Error(err, "failed to set cluster availability of global service", "cluster", clusterName, "key", key, "available", available)
+	}
+}
+
 func (cleaner *clusterCleaner) revokeGlobalService(clusterName string, key client.ObjectKey) {
 	ctx, cancel := context.WithTimeout(context.Background(), cleaner.RequestTimeout)
 	defer cancel()
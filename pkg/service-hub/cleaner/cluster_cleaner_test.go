@@ -32,17 +32,21 @@ var _ = Describe("ClusterCleaner", func() {
 				RequestTimeout:      time.Second,
 				Store:               store,
 				RevokeGlobalService: serviceManager.RevokeGlobalService,
+
+				UnavailableDuration:    time.Second,
+				SetClusterAvailability: serviceManager.SetClusterAvailability,
 			},
 			log: ctrlpkg.Log,
 		}
 	})
 
+	var (
+		globalService apis.GlobalService
+		serviceKey    client.ObjectKey
+		cluster       *types.Cluster
+	)
+
 	Describe("cleanExpiredClusterEndpoints", func() {
-		var (
-			globalService apis.GlobalService
-			serviceKey    client.ObjectKey
-			cluster       *types.Cluster
-		)
 
 		BeforeEach(func() {
 			cluster = store.New("fabedge")
@@ -102,4 +106,56 @@ var _ = Describe("ClusterCleaner", func() {
 			Expect(currentGlobalService.Spec.Endpoints[0]).To(Equal(globalService.Spec.Endpoints[1]))
 		})
 	})
+
+	Describe("updateClusterAvailability", func() {
+		BeforeEach(func() {
+			cluster = store.New("fabedge")
+			globalService = apis.GlobalService{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "nginx",
+					Namespace:   "default",
+					ClusterName: cluster.Name(),
+				},
+				Spec: apis.GlobalServiceSpec{
+					Type: apis.ClusterIP,
+					Endpoints: []apis.Endpoint{
+						{
+							Addresses: []string{"192.168.1.1"},
+							Cluster:   cluster.Name(),
+						},
+					},
+				},
+			}
+			serviceKey = client.ObjectKeyFromObject(&globalService)
+
+			Expect(serviceManager.CreateOrMergeGlobalService(context.Background(), globalService)).To(Succeed())
+			cluster.AddServiceKey(serviceKey)
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(context.Background(), &globalService)).To(Succeed())
+		})
+
+		It("will mark cluster which stops heartbeat as unavailable and keep its endpoints", func() {
+			cluster.SetHeartbeatTime(time.Now().Add(-2 * time.Second))
+			cleaner.updateClusterAvailability()
+
+			var currentGlobalService apis.GlobalService
+			Expect(k8sClient.Get(context.Background(), serviceKey, &currentGlobalService)).To(Succeed())
+			Expect(currentGlobalService.Spec.UnavailableClusters).To(Equal([]string{cluster.Name()}))
+			Expect(currentGlobalService.Spec.Endpoints).To(Equal(globalService.Spec.Endpoints))
+		})
+
+		It("will mark cluster available again after it resumes heartbeat", func() {
+			cluster.SetHeartbeatTime(time.Now().Add(-2 * time.Second))
+			cleaner.updateClusterAvailability()
+
+			cluster.SetHeartbeatTime(time.Now())
+			cleaner.updateClusterAvailability()
+
+			var currentGlobalService apis.GlobalService
+			Expect(k8sClient.Get(context.Background(), serviceKey, &currentGlobalService)).To(Succeed())
+			Expect(currentGlobalService.Spec.UnavailableClusters).To(BeEmpty())
+		})
+	})
 })
@@ -3,6 +3,7 @@ package exporter
 import (
 	"context"
 	"sort"
+	"strconv"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -15,6 +16,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	"github.com/fabedge/fab-dns/pkg/service-hub/types"
 )
 
@@ -22,6 +24,7 @@ const (
 	nameExporter           = "serviceExporter"
 	nameLostServiceRevoker = "lostServiceRevoker"
 	labelGlobalService     = "fabedge.io/global-service"
+	maxWeight              = 1000
 )
 
 type Config struct {
@@ -105,7 +108,18 @@ func (exporter serviceExporter) Reconcile(ctx context.Context, req reconcile.Req
 		serviceType apis.ServiceType
 	)
 
-	if svc.Spec.ClusterIP == corev1.ClusterIPNone {
+	switch {
+	case svc.Spec.Type == corev1.ServiceTypeExternalName:
+		// an ExternalName service has neither cluster IPs nor endpointslices, the
+		// external name is exported as the only address of its endpoint
+		serviceType = apis.ExternalName
+		endpoints = append(endpoints, apis.Endpoint{
+			Addresses: []string{svc.Spec.ExternalName},
+			Cluster:   exporter.ClusterName,
+			Zone:      exporter.Zone,
+			Region:    exporter.Region,
+		})
+	case svc.Spec.ClusterIP == corev1.ClusterIPNone:
 		serviceType = apis.Headless
 		endpoints, err = GetEndpointsOfHeadlessService(exporter.client, ctx, svc.Namespace, svc.Name, ClusterInfo{
 			Name:   exporter.ClusterName,
@@ -116,14 +130,36 @@ func (exporter serviceExporter) Reconcile(ctx context.Context, req reconcile.Req
 			exporter.log.Error(err, "failed to get endpointslices of service")
 			return
 		}
-	} else {
+
+		if svc.Spec.PublishNotReadyAddresses {
+			markEndpointsReady(endpoints)
+		}
+	default:
 		serviceType = apis.ClusterIP
-		endpoints = append(endpoints, apis.Endpoint{
+		endpoint := apis.Endpoint{
 			Addresses: svc.Spec.ClusterIPs,
 			Cluster:   exporter.ClusterName,
 			Zone:      exporter.Zone,
 			Region:    exporter.Region,
-		})
+		}
+
+		// a service without selector has no endpointslices managed by kubernetes,
+		// so its readiness is unknown
+		if len(svc.Spec.Selector) > 0 {
+			endpoint.Conditions.Ready, err = isClusterIPServiceReady(exporter.client, ctx, svc)
+			if err != nil {
+				exporter.log.Error(err, "failed to get endpointslices of service")
+				return
+			}
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if weight := exporter.weightOf(svc); weight != nil {
+		for i := range endpoints {
+			endpoints[i].Weight = weight
+		}
 	}
 
 	globalService := apis.GlobalService{
@@ -131,6 +167,7 @@ func (exporter serviceExporter) Reconcile(ctx context.Context, req reconcile.Req
 			Name:        svc.Name,
 			Namespace:   svc.Namespace,
 			ClusterName: exporter.ClusterName,
+			Annotations: exporter.annotationsOf(svc),
 		},
 		Spec: apis.GlobalServiceSpec{
 			Type:      serviceType,
@@ -151,7 +188,10 @@ func (exporter serviceExporter) Reconcile(ctx context.Context, req reconcile.Req
 }
 
 func (exporter serviceExporter) shouldSkipService(svc corev1.Service) bool {
-	return !isGlobalService(svc.Labels) || svc.Spec.Type != corev1.ServiceTypeClusterIP
+	if !isGlobalService(svc.Labels) {
+		return true
+	}
+	return svc.Spec.Type != corev1.ServiceTypeClusterIP && svc.Spec.Type != corev1.ServiceTypeExternalName
 }
 
 func (exporter serviceExporter) revokeGlobalService(ctx context.Context, serviceKey client.ObjectKey) error {
@@ -173,6 +213,44 @@ func (exporter serviceExporter) revokeGlobalService(ctx context.Context, service
 	return nil
 }
 
+// weightOf returns the weight of endpoints specified by annotation of svc,
+// nil is returned if the weight is not specified or invalid
+func (exporter serviceExporter) weightOf(svc corev1.Service) *int32 {
+	value, found := svc.Annotations[constants.KeyGlobalServiceWeight]
+	if !found {
+		return nil
+	}
+
+	weight, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		exporter.log.Error(err, "invalid weight of service, weight is ignored", "service", client.ObjectKeyFromObject(&svc), "weight", value)
+		return nil
+	}
+	if weight < 0 || weight > maxWeight {
+		exporter.log.Info("weight of service is out of range, weight is ignored", "service", client.ObjectKeyFromObject(&svc), "weight", value, "max", maxWeight)
+		return nil
+	}
+
+	w := int32(weight)
+	return &w
+}
+
+// annotationsOf returns annotations of svc which are propagated to global service,
+// nil is returned if there is none. Invalid TTL is ignored.
+func (exporter serviceExporter) annotationsOf(svc corev1.Service) map[string]string {
+	value, found := svc.Annotations[constants.KeyGlobalServiceTTL]
+	if !found {
+		return nil
+	}
+
+	if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+		exporter.log.Error(err, "invalid ttl of service, ttl is ignored", "service", client.ObjectKeyFromObject(&svc), "ttl", value)
+		return nil
+	}
+
+	return map[string]string{constants.KeyGlobalServiceTTL: value}
+}
+
 func isGlobalService(labels map[string]string) bool {
 	return labels != nil && labels[labelGlobalService] == "true"
 }
@@ -192,24 +270,24 @@ func GetEndpointsOfHeadlessService(cli client.Client, ctx context.Context, names
 	endpointByName := make(map[string]apis.Endpoint)
 	sort.Sort(ByAddressType(endpointSliceList.Items))
 	for _, es := range endpointSliceList.Items {
-		if es.AddressType == discoveryv1.AddressTypeFQDN {
-			continue
-		}
-
+		// endpoints of FQDN endpointslices are exported as they are, fabdns answers
+		// them with CNAME records
 		for _, ep := range es.Endpoints {
-			if e, found := endpointByName[ep.TargetRef.Name]; found {
+			name := endpointNameOf(ep.TargetRef, ep.Addresses)
+			if e, found := endpointByName[name]; found {
 				e.Addresses = append(e.Addresses, ep.Addresses...)
-				endpointByName[ep.TargetRef.Name] = e
+				endpointByName[name] = e
 			} else {
 				endpoint := apis.Endpoint{
-					Addresses: ep.Addresses,
-					Hostname:  ep.Hostname,
-					TargetRef: ep.TargetRef,
-					Cluster:   cluster.Name,
-					Zone:      cluster.Zone,
-					Region:    cluster.Region,
+					Addresses:  ep.Addresses,
+					Hostname:   ep.Hostname,
+					TargetRef:  ep.TargetRef,
+					Conditions: ep.Conditions,
+					Cluster:    cluster.Name,
+					Zone:       cluster.Zone,
+					Region:     cluster.Region,
 				}
-				endpointByName[ep.TargetRef.Name] = endpoint
+				endpointByName[name] = endpoint
 			}
 		}
 	}
@@ -223,6 +301,55 @@ func GetEndpointsOfHeadlessService(cli client.Client, ctx context.Context, names
 	return endpoints, nil
 }
 
+// endpointNameOf returns the name by which addresses of the same endpoint in endpointslices
+// of different address types are merged. Endpoints without target, which are common in
+// FQDN endpointslices, are named by their first address.
+func endpointNameOf(targetRef *corev1.ObjectReference, addresses []string) string {
+	if targetRef != nil {
+		return targetRef.Name
+	}
+	if len(addresses) > 0 {
+		return addresses[0]
+	}
+	return ""
+}
+
+// isClusterIPServiceReady checks if any endpoint of service is ready, if the service
+// publishes not ready addresses, any endpoint is considered as ready.
+func isClusterIPServiceReady(cli client.Client, ctx context.Context, svc corev1.Service) (*bool, error) {
+	var endpointSliceList discoveryv1.EndpointSliceList
+	err := cli.List(ctx, &endpointSliceList,
+		client.InNamespace(svc.Namespace),
+		client.MatchingLabels{
+			"kubernetes.io/service-name": svc.Name,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := false
+	for _, es := range endpointSliceList.Items {
+		for _, ep := range es.Endpoints {
+			if svc.Spec.PublishNotReadyAddresses || ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				ready = true
+				return &ready, nil
+			}
+		}
+	}
+
+	return &ready, nil
+}
+
+// markEndpointsReady marks all endpoints as ready, it's used for services which publish
+// not ready addresses, like what EndpointSlice controller does.
+func markEndpointsReady(endpoints []apis.Endpoint) {
+	ready := true
+	for i := range endpoints {
+		endpoints[i].Conditions.Ready = &ready
+	}
+}
+
 type ByName []apis.Endpoint
 type ByAddressType []discoveryv1.EndpointSlice
 type ClusterInfo struct {
@@ -231,7 +358,8 @@ type ClusterInfo struct {
 
 func (a ByName) Len() int           { return len(a) }
 func (a ByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByName) Less(i, j int) bool { return a[i].TargetRef.Name < a[j].TargetRef.Name }
+func (a ByName) Less(i, j int) bool { return a.name(i) < a.name(j) }
+func (a ByName) name(i int) string  { return endpointNameOf(a[i].TargetRef, a[i].Addresses) }
 
 func (a ByAddressType) Len() int           { return len(a) }
 func (a ByAddressType) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -15,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	testutil "github.com/fabedge/fab-dns/pkg/util/test"
 )
 
@@ -87,6 +88,120 @@ var _ = Describe("ServiceExporter", func() {
 			})
 		})
 
+		When("it is marked as global-service with weight", func() {
+			It("will export this service with the weight", func() {
+				svc.Annotations = map[string]string{constants.KeyGlobalServiceWeight: "10"}
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+
+				weight := int32(10)
+				td.expectServiceExported(&svc, apis.ClusterIP, []apis.Endpoint{
+					{
+						Addresses: svc.Spec.ClusterIPs,
+						Cluster:   td.cluster,
+						Zone:      td.zone,
+						Region:    td.region,
+						Weight:    &weight,
+					},
+				})
+			})
+
+			It("will ignore invalid weight", func() {
+				svc.Annotations = map[string]string{constants.KeyGlobalServiceWeight: "2000"}
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+
+				td.expectServiceExported(&svc, apis.ClusterIP, []apis.Endpoint{
+					{
+						Addresses: svc.Spec.ClusterIPs,
+						Cluster:   td.cluster,
+						Zone:      td.zone,
+						Region:    td.region,
+					},
+				})
+			})
+		})
+
+		When("it is marked as global-service with ttl", func() {
+			It("will export this service with the ttl annotation", func() {
+				svc.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "300"}
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+
+				Expect(td.exportedGlobalService.Annotations).To(Equal(map[string]string{constants.KeyGlobalServiceTTL: "300"}))
+			})
+
+			It("will ignore invalid ttl", func() {
+				svc.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "-1"}
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+
+				Expect(td.exportedGlobalService.Annotations).To(BeEmpty())
+			})
+		})
+
+		When("it is marked as global-service and has a selector", func() {
+			BeforeEach(func() {
+				svc.Spec.Selector = map[string]string{"app": "nginx"}
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+			})
+
+			It("will export this service as not ready if it has no ready endpoints", func() {
+				notReady := false
+				td.expectServiceExported(&svc, apis.ClusterIP, []apis.Endpoint{
+					{
+						Addresses:  svc.Spec.ClusterIPs,
+						Cluster:    td.cluster,
+						Zone:       td.zone,
+						Region:     td.region,
+						Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+					},
+				})
+			})
+
+			It("will export this service as ready if it has ready endpoints", func() {
+				managedByController, ready := true, true
+				endpointslice := discoveryv1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "nginx-123456",
+						Namespace: "default",
+						Labels: map[string]string{
+							"kubernetes.io/service-name": "nginx",
+						},
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								APIVersion: "v1",
+								Kind:       "Service",
+								Name:       svc.Name,
+								UID:        "123456",
+								Controller: &managedByController,
+							},
+						},
+					},
+					AddressType: discoveryv1.AddressTypeIPv4,
+					Endpoints: []discoveryv1.Endpoint{
+						{
+							Addresses:  []string{"192.168.1.1"},
+							Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+						},
+					},
+				}
+				td.createObject(&endpointslice)
+				td.expectExporterReconcile(&svc)
+
+				td.expectServiceExported(&svc, apis.ClusterIP, []apis.Endpoint{
+					{
+						Addresses:  svc.Spec.ClusterIPs,
+						Cluster:    td.cluster,
+						Zone:       td.zone,
+						Region:     td.region,
+						Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					},
+				})
+			})
+		})
+
 		When("it is not marked as global service", func() {
 			It("will be ignored and will not be exported", func() {
 				svc.Labels = nil
@@ -109,6 +224,7 @@ var _ = Describe("ServiceExporter", func() {
 			hostname1 := "mysql-1"
 			hostname2 := "mysql-2"
 			managedByController := true
+			ready := true
 
 			port := corev1.ServicePort{
 				Name:     "default",
@@ -155,8 +271,9 @@ var _ = Describe("ServiceExporter", func() {
 				},
 				Endpoints: []discoveryv1.Endpoint{
 					{
-						Addresses: []string{"192.168.1.1"},
-						Hostname:  &hostname1,
+						Addresses:  []string{"192.168.1.1"},
+						Hostname:   &hostname1,
+						Conditions: discoveryv1.EndpointConditions{Ready: &ready},
 						TargetRef: &corev1.ObjectReference{
 							Kind:      "Pod",
 							Name:      hostname1,
@@ -202,8 +319,9 @@ var _ = Describe("ServiceExporter", func() {
 				},
 				Endpoints: []discoveryv1.Endpoint{
 					{
-						Addresses: []string{"fd85:ee78:d8a6:8607::1:1"},
-						Hostname:  &hostname1,
+						Addresses:  []string{"fd85:ee78:d8a6:8607::1:1"},
+						Hostname:   &hostname1,
+						Conditions: discoveryv1.EndpointConditions{Ready: &ready},
 						TargetRef: &corev1.ObjectReference{
 							Kind:      "Pod",
 							Name:      hostname1,
@@ -239,17 +357,47 @@ var _ = Describe("ServiceExporter", func() {
 				var expectedEndpoints []apis.Endpoint
 				for i, endpoint := range endpointslice4.Endpoints {
 					expectedEndpoints = append(expectedEndpoints, apis.Endpoint{
-						Addresses: append(endpoint.Addresses, endpointslice6.Endpoints[i].Addresses...),
-						Hostname:  endpoint.Hostname,
-						TargetRef: endpoint.TargetRef,
-						Cluster:   td.cluster,
-						Zone:      td.zone,
-						Region:    td.region,
+						Addresses:  append(endpoint.Addresses, endpointslice6.Endpoints[i].Addresses...),
+						Hostname:   endpoint.Hostname,
+						TargetRef:  endpoint.TargetRef,
+						Conditions: endpoint.Conditions,
+						Cluster:    td.cluster,
+						Zone:       td.zone,
+						Region:     td.region,
 					})
 				}
 				td.expectServiceExported(&svc, apis.Headless, expectedEndpoints)
 			})
 
+			When("conditions of endpoints are changed", func() {
+				It("will export this service with the new conditions", func() {
+					notReady := false
+					endpointslice4.Endpoints[0].Conditions.Ready = &notReady
+					endpointslice6.Endpoints[0].Conditions.Ready = &notReady
+					td.updateObject(&endpointslice4)
+					td.expectExporterReconcile(&svc)
+
+					Expect(td.exportedGlobalService.Spec.Endpoints[0].Conditions.Ready).To(Equal(&notReady))
+				})
+			})
+
+			When("the service publishes not ready addresses", func() {
+				It("will export all endpoints as ready", func() {
+					notReady := false
+					endpointslice4.Endpoints[0].Conditions.Ready = &notReady
+					td.updateObject(&endpointslice4)
+					td.expectExporterReconcile(&svc)
+
+					svc.Spec.PublishNotReadyAddresses = true
+					td.updateObject(&svc)
+					td.expectExporterReconcile(&svc)
+
+					for _, endpoint := range td.exportedGlobalService.Spec.Endpoints {
+						Expect(*endpoint.Conditions.Ready).To(BeTrue())
+					}
+				})
+			})
+
 			When("the global-service marker is removed", func() {
 				Specify("it and endpoints will be revoked", func() {
 					svc.Labels = nil
@@ -261,6 +409,39 @@ var _ = Describe("ServiceExporter", func() {
 			})
 		})
 
+		When("it has FQDN endpointslices", func() {
+			It("will export endpoints of domain names", func() {
+				td.createObject(&svc)
+				td.expectExporterReconcile(&svc)
+
+				endpointsliceFQDN := discoveryv1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "mysql-fqdn",
+						Namespace: "default",
+						Labels: map[string]string{
+							"kubernetes.io/service-name": "mysql",
+						},
+					},
+					AddressType: discoveryv1.AddressTypeFQDN,
+					Endpoints: []discoveryv1.Endpoint{
+						{Addresses: []string{"mysql.example.com"}},
+					},
+				}
+				td.createObject(&endpointsliceFQDN)
+				td.createObject(&endpointslice4)
+				td.expectExporterReconcile(&svc)
+
+				Expect(td.exportedGlobalService.Spec.Type).To(Equal(apis.Headless))
+				Expect(td.exportedGlobalService.Spec.Endpoints).To(ContainElement(apis.Endpoint{
+					Addresses: []string{"mysql.example.com"},
+					Cluster:   td.cluster,
+					Zone:      td.zone,
+					Region:    td.region,
+				}))
+				Expect(td.exportedGlobalService.Spec.Endpoints).To(HaveLen(3))
+			})
+		})
+
 		When("it is not marked as global service", func() {
 			It("will be ignored and will not be exported", func() {
 				svc.Labels = nil
@@ -271,6 +452,48 @@ var _ = Describe("ServiceExporter", func() {
 			})
 		})
 	})
+
+	Context("An ExternalName service", func() {
+		var svc corev1.Service
+
+		BeforeEach(func() {
+			svc = corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "database",
+					Namespace: "default",
+					Labels: map[string]string{
+						labelGlobalService: "true",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "database.example.com",
+				},
+			}
+		})
+
+		It("will export the external name as the address of its endpoint", func() {
+			td.createObject(&svc)
+			td.expectExporterReconcile(&svc)
+
+			td.expectServiceExported(&svc, apis.ExternalName, []apis.Endpoint{
+				{
+					Addresses: []string{"database.example.com"},
+					Cluster:   td.cluster,
+					Zone:      td.zone,
+					Region:    td.region,
+				},
+			})
+		})
+
+		It("will be ignored if it is not marked as global service", func() {
+			svc.Labels = nil
+			td.createObject(&svc)
+			td.expectExporterReconcile(&svc)
+
+			td.expectServiceNotExported(&svc)
+		})
+	})
 })
 
 type testDriver struct {
@@ -131,6 +131,7 @@ func (importer *globalServiceImporter) createOrUpdateGlobalService(sourceService
 
 		service.Labels[constants.KeyOriginResourceVersion] = sourceService.ResourceVersion
 		service.Spec = sourceService.Spec
+		types.CopyAnnotation(service, &sourceService, constants.KeyGlobalServiceTTL)
 
 		return nil
 	})
@@ -109,6 +109,16 @@ var _ = Describe("GlobalServiceImporter", func() {
 				importer.createOrUpdateGlobalService(globalService)
 				expectGlobalServiceSaved(globalService)
 			})
+
+			It("will copy ttl annotation of the global service", func() {
+				globalService.ResourceVersion = "1234567"
+				globalService.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "300"}
+				importer.createOrUpdateGlobalService(globalService)
+
+				var savedService apis.GlobalService
+				Expect(k8sClient.Get(context.Background(), serviceKey, &savedService)).To(Succeed())
+				Expect(savedService.Annotations).To(HaveKeyWithValue(constants.KeyGlobalServiceTTL, "300"))
+			})
 		})
 
 		Context("when are not allowed to create namespace", func() {
@@ -50,6 +50,11 @@ func init() {
 const (
 	ModeServer = "server"
 	ModeClient = "client"
+
+	defaultServiceImportInterval = time.Minute
+	// a cluster sends heartbeats when importing services, so the unavailable duration
+	// must cover a few import intervals, or healthy clusters may be marked unavailable
+	defaultClusterUnavailableTime = 3 * defaultServiceImportInterval
 )
 
 var (
@@ -71,18 +76,20 @@ type Options struct {
 	TLSCertFile              string
 	TLSCACertFile            string
 
-	ClusterExpireTime     time.Duration
-	ServiceImportInterval time.Duration
-	RequestTimeout        time.Duration
-	AllowCreateNamespace  bool
+	ClusterExpireTime      time.Duration
+	ClusterUnavailableTime time.Duration
+	ServiceImportInterval  time.Duration
+	RequestTimeout         time.Duration
+	AllowCreateNamespace   bool
 
 	Manager      ctrlpkg.Manager
 	ClusterStore *types.ClusterStore
 	APIServer    *http.Server
 	Client       fclient.Interface
 
-	ExportGlobalService types.ExportGlobalServiceFunc
-	RevokeGlobalService types.RevokeGlobalServiceFunc
+	ExportGlobalService    types.ExportGlobalServiceFunc
+	RevokeGlobalService    types.RevokeGlobalServiceFunc
+	SetClusterAvailability types.SetClusterAvailabilityFunc
 }
 
 func (opts *Options) AddFlags(flag *pflag.FlagSet) {
@@ -99,7 +106,8 @@ func (opts *Options) AddFlags(flag *pflag.FlagSet) {
 	flag.StringVar(&opts.TLSCACertFile, "tls-ca-cert-file", "", "The CA cert file for API server/client")
 
 	flag.DurationVar(&opts.ClusterExpireTime, "cluster-expire-duration", 5*time.Minute, "Expiration time after cluster stops heartbeat")
-	flag.DurationVar(&opts.ServiceImportInterval, "service-import-interval", time.Minute, "The interval between each services importing routine")
+	flag.DurationVar(&opts.ClusterUnavailableTime, "cluster-unavailable-duration", defaultClusterUnavailableTime, "The duration after which a cluster stopping heartbeat is marked unavailable, it should be greater than service-import-interval of clients, 0 means never")
+	flag.DurationVar(&opts.ServiceImportInterval, "service-import-interval", defaultServiceImportInterval, "The interval between each services importing routine")
 	flag.DurationVar(&opts.RequestTimeout, "request-timeout", 5*time.Second, "Timeout for kubernetes API request")
 	flag.BoolVar(&opts.AllowCreateNamespace, "allow-create-namespace", true, "Determine if service-hub are allowed to create namespace if needed")
 }
@@ -178,6 +186,7 @@ func (opts *Options) initAPIServer() (err error) {
 	globalServiceManager := types.NewGlobalServiceManager(opts.Manager.GetClient(), opts.AllowCreateNamespace)
 	opts.ExportGlobalService = globalServiceManager.CreateOrMergeGlobalService
 	opts.RevokeGlobalService = globalServiceManager.RevokeGlobalService
+	opts.SetClusterAvailability = globalServiceManager.SetClusterAvailability
 
 	opts.APIServer, err = apiserver.New(apiserver.Config{
 		Address:               opts.APIServerListenAddress,
@@ -261,12 +270,20 @@ func (opts Options) initManagerRunnables() (err error) {
 			return err
 		}
 
+		// clusters should be checked frequently enough to be marked unavailable in time
+		interval := opts.ClusterExpireTime
+		if opts.ClusterUnavailableTime > 0 && opts.ClusterUnavailableTime < interval {
+			interval = opts.ClusterUnavailableTime
+		}
+
 		if err = cleaner.AddToManager(cleaner.Config{
-			Manager:             opts.Manager,
-			Store:               opts.ClusterStore,
-			Interval:            opts.ClusterExpireTime,
-			RequestTimeout:      opts.RequestTimeout,
-			RevokeGlobalService: opts.RevokeGlobalService,
+			Manager:                opts.Manager,
+			Store:                  opts.ClusterStore,
+			Interval:               interval,
+			RequestTimeout:         opts.RequestTimeout,
+			RevokeGlobalService:    opts.RevokeGlobalService,
+			UnavailableDuration:    opts.ClusterUnavailableTime,
+			SetClusterAvailability: opts.SetClusterAvailability,
 		}); err != nil {
 			log.Error(err, "failed to add cluster cleaner to manager")
 			return err
@@ -361,6 +378,7 @@ func (opts Options) recordGlobalServicesByCluster() {
 			if opts.ClusterStore.Get(endpoint.Cluster) == nil {
 				cluster := opts.ClusterStore.New(endpoint.Cluster)
 				cluster.SetExpireTime(time.Now().Add(opts.ClusterExpireTime))
+				cluster.SetHeartbeatTime(time.Now())
 
 				cluster.AddServiceKey(client.ObjectKey{
 					Name:      gs.Name,
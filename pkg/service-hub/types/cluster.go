@@ -13,6 +13,7 @@ type Cluster struct {
 	name          string
 	serviceKeySet ObjectKeySet
 	expireTime    time.Time
+	heartbeatTime time.Time
 	lock          sync.RWMutex
 }
 
@@ -53,6 +54,20 @@ func (c *Cluster) IsExpired() bool {
 	return !c.expireTime.IsZero() && c.expireTime.Before(time.Now())
 }
 
+func (c *Cluster) SetHeartbeatTime(heartbeatTime time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.heartbeatTime = heartbeatTime
+}
+
+// IsUnavailable returns true if the cluster has not sent any request for the duration
+func (c *Cluster) IsUnavailable(duration time.Duration) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return !c.heartbeatTime.IsZero() && c.heartbeatTime.Add(duration).Before(time.Now())
+}
+
 func (c *Cluster) GetAllServiceKeys() []client.ObjectKey {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -127,3 +142,15 @@ func (store *ClusterStore) GetExpiredClusters() []*Cluster {
 
 	return clusters
 }
+
+func (store *ClusterStore) GetAllClusters() []*Cluster {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	clusters := make([]*Cluster, 0, len(store.clusters))
+	for _, c := range store.clusters {
+		clusters = append(clusters, c)
+	}
+
+	return clusters
+}
@@ -36,4 +36,18 @@ var _ = Describe("ClusterStore", func() {
 		Expect(len(clusters)).To(Equal(1))
 		Expect(clusters[0]).To(Equal(expiredCluster))
 	})
+
+	It("IsUnavailable will return true if cluster stops heartbeat for the duration", func() {
+		cluster := store.New("c1")
+		Expect(cluster.IsUnavailable(time.Second)).To(BeFalse())
+
+		cluster.SetHeartbeatTime(time.Now().Add(-2 * time.Second))
+		Expect(cluster.IsUnavailable(time.Second)).To(BeTrue())
+		Expect(cluster.IsUnavailable(time.Minute)).To(BeFalse())
+	})
+
+	It("GetAllClusters will return all clusters", func() {
+		c1, c2 := store.New("c1"), store.New("c2")
+		Expect(store.GetAllClusters()).To(ConsistOf(c1, c2))
+	})
 })
@@ -2,8 +2,29 @@ package types
 
 import (
 	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
 )
 
 type ExportGlobalServiceFunc func(ctx context.Context, service apis.GlobalService) error
 type RevokeGlobalServiceFunc func(ctx context.Context, clusterName, namespace, serviceName string) error
+type SetClusterAvailabilityFunc func(ctx context.Context, clusterName, namespace, serviceName string, available bool) error
+
+// CopyAnnotation copies annotation of key from source to target, the annotation of
+// target is removed if source doesn't have it
+func CopyAnnotation(target, source client.Object, key string) {
+	annotations := target.GetAnnotations()
+	value, found := source.GetAnnotations()[key]
+	switch {
+	case found:
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[key] = value
+	case annotations != nil:
+		delete(annotations, key)
+	}
+	target.SetAnnotations(annotations)
+}
@@ -10,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	nsutil "github.com/fabedge/fab-dns/pkg/util/namespace"
 )
 
@@ -22,6 +23,10 @@ type GlobalServiceManager interface {
 	// specified by namespace/name, if no endpoints left, the global service will
 	// be also deleted
 	RevokeGlobalService(ctx context.Context, clusterName, namespace, serviceName string) error
+
+	// SetClusterAvailability will add cluster to or remove cluster from unavailable
+	// clusters of global service specified by namespace/name
+	SetClusterAvailability(ctx context.Context, clusterName, namespace, serviceName string, available bool) error
 }
 
 var _ GlobalServiceManager = &globalServiceManager{}
@@ -73,7 +78,10 @@ func (manager *globalServiceManager) CreateOrMergeGlobalService(ctx context.Cont
 			Type:      externalService.Spec.Type,
 			Ports:     externalService.Spec.Ports,
 			Endpoints: allEndpoints,
+			// a cluster which is exporting services is available
+			UnavailableClusters: removeString(localService.Spec.UnavailableClusters, externalService.ClusterName),
 		}
+		mergeTTLAnnotation(localService, externalService)
 
 		return nil
 	})
@@ -95,6 +103,8 @@ func (manager *globalServiceManager) RevokeGlobalService(ctx context.Context, cl
 	}
 
 	svc.Spec.Endpoints = removeEndpoints(svc.Spec.Endpoints, clusterName)
+	svc.Spec.UnavailableClusters = removeString(svc.Spec.UnavailableClusters, clusterName)
+	removeTTLAnnotation(&svc, clusterName)
 	if len(svc.Spec.Endpoints) == 0 {
 		err = manager.client.Delete(ctx, &svc)
 	} else {
@@ -104,6 +114,61 @@ func (manager *globalServiceManager) RevokeGlobalService(ctx context.Context, cl
 	return err
 }
 
+func (manager *globalServiceManager) SetClusterAvailability(ctx context.Context, clusterName, namespace, serviceName string, available bool) error {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	var (
+		svc apis.GlobalService
+		key = client.ObjectKey{Name: serviceName, Namespace: namespace}
+	)
+	err := manager.client.Get(ctx, key, &svc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	unavailable := containsString(svc.Spec.UnavailableClusters, clusterName)
+	switch {
+	case available && unavailable:
+		svc.Spec.UnavailableClusters = removeString(svc.Spec.UnavailableClusters, clusterName)
+	case !available && !unavailable:
+		svc.Spec.UnavailableClusters = append(svc.Spec.UnavailableClusters, clusterName)
+	default:
+		return nil
+	}
+
+	return manager.client.Update(ctx, &svc)
+}
+
+// mergeTTLAnnotation takes TTL annotation from the service exported by a cluster, the TTL
+// set by another cluster is kept if the exported service doesn't have the annotation
+func mergeTTLAnnotation(globalService *apis.GlobalService, externalService apis.GlobalService) {
+	ttl, found := externalService.Annotations[constants.KeyGlobalServiceTTL]
+	if !found {
+		removeTTLAnnotation(globalService, externalService.ClusterName)
+		return
+	}
+
+	if globalService.Annotations == nil {
+		globalService.Annotations = make(map[string]string)
+	}
+	globalService.Annotations[constants.KeyGlobalServiceTTL] = ttl
+	globalService.Annotations[constants.KeyGlobalServiceTTLCluster] = externalService.ClusterName
+}
+
+// removeTTLAnnotation removes TTL annotation of global service only if it's set by the cluster
+func removeTTLAnnotation(globalService *apis.GlobalService, cluster string) {
+	if globalService.Annotations[constants.KeyGlobalServiceTTLCluster] != cluster {
+		return
+	}
+
+	delete(globalService.Annotations, constants.KeyGlobalServiceTTL)
+	delete(globalService.Annotations, constants.KeyGlobalServiceTTLCluster)
+}
+
 func removeEndpoints(endpoints []apis.Endpoint, cluster string) []apis.Endpoint {
 	for i := 0; i < len(endpoints); {
 		if endpoints[i].Cluster == cluster {
@@ -115,3 +180,24 @@ func removeEndpoints(endpoints []apis.Endpoint, cluster string) []apis.Endpoint
 
 	return endpoints
 }
+
+func removeString(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
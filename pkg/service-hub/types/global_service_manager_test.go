@@ -10,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fab-dns/pkg/apis/v1alpha1"
+	"github.com/fabedge/fab-dns/pkg/constants"
 	"github.com/fabedge/fab-dns/pkg/service-hub/types"
 	testutil "github.com/fabedge/fab-dns/pkg/util/test"
 )
@@ -61,6 +62,19 @@ var _ = Describe("GlobalServiceManager", func() {
 						Expect(service.Spec.Ports).NotTo(Equal(serviceFromBeijing.Spec.Ports))
 					})
 
+					It("will take ttl annotation from request", func() {
+						serviceFromShanghai.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "300"}
+						td.createOrMergeGlobalService(serviceFromShanghai)
+						Expect(td.getService().Annotations).To(HaveKeyWithValue(constants.KeyGlobalServiceTTL, "300"))
+
+						td.createOrMergeGlobalService(serviceFromBeijing)
+						Expect(td.getService().Annotations).To(HaveKeyWithValue(constants.KeyGlobalServiceTTL, "300"))
+
+						serviceFromShanghai.Annotations = nil
+						td.createOrMergeGlobalService(serviceFromShanghai)
+						Expect(td.getService().Annotations).NotTo(HaveKey(constants.KeyGlobalServiceTTL))
+					})
+
 					It("will append endpoints from request", func() {
 						service := td.getService()
 						Expect(service.Spec.Endpoints).To(ConsistOf(
@@ -177,11 +191,31 @@ var _ = Describe("GlobalServiceManager", func() {
 			Expect(service.Spec.Endpoints).To(Equal(serviceFromShanghai.Spec.Endpoints))
 		})
 
+		It("will remove ttl annotation only if it's set by this cluster", func() {
+			serviceFromShanghai.Annotations = map[string]string{constants.KeyGlobalServiceTTL: "300"}
+			td.createOrMergeGlobalService(serviceFromShanghai)
+
+			td.revokeGlobalService(serviceFromBeijing)
+			Expect(td.getService().Annotations).To(HaveKeyWithValue(constants.KeyGlobalServiceTTL, "300"))
+
+			td.createOrMergeGlobalService(serviceFromBeijing)
+			td.revokeGlobalService(serviceFromShanghai)
+			Expect(td.getService().Annotations).NotTo(HaveKey(constants.KeyGlobalServiceTTL))
+		})
+
 		It("the global service will be deleted if no endpoints are left", func() {
 			td.revokeGlobalService(serviceFromShanghai)
 			td.expectServiceNotFound()
 		})
 
+		It("will remove this cluster from unavailable clusters", func() {
+			Expect(td.manager.SetClusterAvailability(context.Background(), "shanghai", td.namespace, td.serviceName, false)).To(Succeed())
+			td.revokeGlobalService(serviceFromShanghai)
+
+			td.createOrMergeGlobalService(serviceFromBeijing)
+			Expect(td.getService().Spec.UnavailableClusters).To(BeEmpty())
+		})
+
 		It("will just return without error if target global service not found", func() {
 			td.revokeGlobalService(apis.GlobalService{
 				ObjectMeta: metav1.ObjectMeta{
@@ -192,6 +226,44 @@ var _ = Describe("GlobalServiceManager", func() {
 			})
 		})
 	})
+
+	Describe("SetClusterAvailability", func() {
+		JustBeforeEach(func() {
+			td.createOrMergeGlobalService(serviceFromBeijing)
+			td.createOrMergeGlobalService(serviceFromShanghai)
+		})
+
+		It("will add unavailable cluster to global service only once", func() {
+			td.setClusterAvailability("beijing", false)
+			td.setClusterAvailability("beijing", false)
+
+			service := td.getService()
+			Expect(service.Spec.UnavailableClusters).To(Equal([]string{"beijing"}))
+			Expect(service.Spec.Endpoints).To(ConsistOf(
+				serviceFromBeijing.Spec.Endpoints[0],
+				serviceFromShanghai.Spec.Endpoints[0],
+			))
+		})
+
+		It("will remove available cluster from global service", func() {
+			td.setClusterAvailability("beijing", false)
+			td.setClusterAvailability("beijing", true)
+
+			Expect(td.getService().Spec.UnavailableClusters).To(BeEmpty())
+		})
+
+		It("will mark cluster available when the cluster exports service again", func() {
+			td.setClusterAvailability("beijing", false)
+			td.setClusterAvailability("shanghai", false)
+			td.createOrMergeGlobalService(serviceFromBeijing)
+
+			Expect(td.getService().Spec.UnavailableClusters).To(Equal([]string{"shanghai"}))
+		})
+
+		It("will just return without error if target global service not found", func() {
+			Expect(td.manager.SetClusterAvailability(context.Background(), "beijing", "default", "not-found", false)).To(Succeed())
+		})
+	})
 })
 
 type testDriver struct {
@@ -285,6 +357,10 @@ func (td *testDriver) revokeGlobalService(svc apis.GlobalService) {
 	Expect(td.manager.RevokeGlobalService(context.Background(), svc.ClusterName, svc.Namespace, svc.Name)).To(Succeed())
 }
 
+func (td *testDriver) setClusterAvailability(clusterName string, available bool) {
+	Expect(td.manager.SetClusterAvailability(context.Background(), clusterName, td.namespace, td.serviceName, available)).To(Succeed())
+}
+
 func (td *testDriver) getService() apis.GlobalService {
 	return testutil.ExpectGetGlobalService(k8sClient, client.ObjectKey{Name: td.serviceName, Namespace: td.namespace})
 }